@@ -0,0 +1,60 @@
+package swagvalidator
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/miketonks/swag"
+	"github.com/miketonks/swag/swagger"
+)
+
+// SwaggerValidatorChi chi middleware.
+// Routes are identified by their chi route pattern, so endpoints must be registered with their swagger path,
+// e.g. /pet/{petId} with basePath applied, as api.Walk provides them.
+func SwaggerValidatorChi(api *swagger.API, opts ...Option) func(http.Handler) http.Handler {
+
+	v := NewValidator(api, opts...)
+
+	// This part runs at runtime, with context for individual request
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.RouteContext(r.Context())
+			if rctx == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			pattern := rctx.RoutePattern()
+			urlParam := func(key string) string {
+				return chi.URLParam(r, key)
+			}
+
+			// Middleware added with Use runs before chi has routed the request, so match the route here.
+			// In a sub router or a mounted router, the pattern is the partial one of its parent, e.g. /api/*,
+			// and the full path is matched against the top router.
+			if (pattern == "" || strings.HasSuffix(pattern, "/*")) && rctx.Routes != nil {
+				tctx := chi.NewRouteContext()
+				path := r.URL.RawPath
+				if path == "" {
+					path = r.URL.Path
+				}
+				if !rctx.Routes.Match(tctx, r.Method, path) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				pattern = tctx.RoutePattern()
+				urlParam = tctx.URLParam
+			}
+
+			// chi patterns may restrict params with a regexp, e.g. /pet/{petId:[0-9]+}
+			pattern = stripVariablePatterns(pattern)
+			pathParams := map[string]string{}
+			for _, name := range pathParamNames(pattern) {
+				pathParams[name] = urlParam(name)
+			}
+
+			v.serveHTTP(w, r, RouteKey(r.Method, swag.ColonPath(pattern)), pathParams, next)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package swagvalidator
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/miketonks/swag/swagger"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// SwaggerValidatorFiber fiber middleware.
+// Added to a route, the endpoint is looked up by method and c.Route().Path, registered as the colon path
+// of the swagger path. Added with app.Use, requests are matched against the swagger path templates instead.
+func SwaggerValidatorFiber(api *swagger.API, opts ...Option) fiber.Handler {
+
+	v := NewValidator(api, opts...)
+	options := v.options
+	routes := newRouteTrie(api)
+
+	// This part runs at runtime, with context for individual request
+	return func(c *fiber.Ctx) error {
+		r := &http.Request{}
+		if err := fasthttpadaptor.ConvertRequest(c.Context(), r, true); err != nil {
+			return errorResponseFiber(c, options, ErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Validation error",
+				Details: map[string]string{
+					"body": "Failed to read request",
+				},
+			})
+		}
+
+		// multipart files over the memory limit are stored in temp files
+		defer func() {
+			if r.MultipartForm != nil {
+				r.MultipartForm.RemoveAll()
+			}
+		}()
+
+		route := c.Route()
+		key := RouteKey(c.Method(), route.Path)
+		pathParams := map[string]string{}
+		if v.hasRoute(key) && matchesWholePath(route.Path, c.Path()) {
+			for _, name := range route.Params {
+				pathParams[name] = c.Params(name)
+			}
+		} else {
+			// The route of a middleware added with app.Use is its prefix, not the endpoint path
+			pattern, params, found := routes.match(c.Method(), r.URL.EscapedPath())
+			if !found {
+				return c.Next()
+			}
+			key, pathParams = RouteKey(c.Method(), pattern), params
+		}
+
+		result := v.ValidateRequest(r, key, pathParams)
+		if !result.Valid() {
+			return errorResponseFiber(c, options, *result.Error)
+		}
+		return c.Next()
+	}
+}
+
+// matchesWholePath returns true if the route path has as many segments as the request path.
+// Routes of middlewares added with app.Use match any path under their prefix, and have the method of the request.
+func matchesWholePath(routePath, path string) bool {
+	return strings.Count(strings.TrimRight(routePath, "/"), "/") == strings.Count(strings.TrimRight(path, "/"), "/")
+}
+
+func errorResponseFiber(c *fiber.Ctx, o *Options, resp ErrorResponse) error {
+	if o.ReturnErrors {
+		return resp
+	}
+	return c.Status(resp.StatusCode).JSON(resp)
+}
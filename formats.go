@@ -0,0 +1,53 @@
+package swagvalidator
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// numberFormats are the swagger number formats, checked for range by the format checkers below
+var numberFormats = map[string]gojsonschema.FormatChecker{
+	"int32":  rangeFormatChecker{min: big.NewRat(math.MinInt32, 1), max: big.NewRat(math.MaxInt32, 1)},
+	"int64":  rangeFormatChecker{min: big.NewRat(math.MinInt64, 1), max: big.NewRat(math.MaxInt64, 1)},
+	"float":  rangeFormatChecker{min: new(big.Rat).SetFloat64(-math.MaxFloat32), max: new(big.Rat).SetFloat64(math.MaxFloat32)},
+	"double": rangeFormatChecker{min: new(big.Rat).SetFloat64(-math.MaxFloat64), max: new(big.Rat).SetFloat64(math.MaxFloat64)},
+}
+
+var registerFormats sync.Once
+
+// registerFormatCheckers adds the number format checkers to the gojsonschema checkers, once
+func registerFormatCheckers() {
+	registerFormats.Do(func() {
+		for name, checker := range numberFormats {
+			gojsonschema.FormatCheckers.Add(name, checker)
+		}
+	})
+}
+
+// rangeFormatChecker checks a number is within the range of its format
+type rangeFormatChecker struct {
+	min, max *big.Rat
+}
+
+// IsFormat checks numbers, gojsonschema passes them as *big.Rat. Other values are left to the type check.
+func (f rangeFormatChecker) IsFormat(input interface{}) bool {
+	n, ok := input.(*big.Rat)
+	if !ok {
+		return true
+	}
+	return n.Cmp(f.min) >= 0 && n.Cmp(f.max) <= 0
+}
+
+// inFormatRange returns false if n is outside the range of the number format, other formats accept any number
+func inFormatRange(n json.Number, format string) bool {
+	checker, ok := numberFormats[format]
+	if !ok {
+		return true
+	}
+	r, ok := new(big.Rat).SetString(string(n))
+	return !ok || checker.IsFormat(r)
+}
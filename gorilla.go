@@ -0,0 +1,61 @@
+package swagvalidator
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/miketonks/swag"
+	"github.com/miketonks/swag/swagger"
+)
+
+// SwaggerValidatorGorilla gorilla/mux middleware.
+// Routes are identified by their path template, so endpoints must be registered with their swagger path,
+// e.g. /pet/{petId} with basePath applied, as api.Walk provides them. Routes not in the spec are passed through.
+func SwaggerValidatorGorilla(api *swagger.API, opts ...Option) func(http.Handler) http.Handler {
+
+	v := NewValidator(api, opts...)
+
+	// This part runs at runtime, with context for individual request
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			template, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := RouteKey(r.Method, swag.ColonPath(stripVariablePatterns(template)))
+			v.serveHTTP(w, r, key, mux.Vars(r), next)
+		})
+	}
+}
+
+// stripVariablePatterns removes the regular expressions from the variables of a gorilla/mux
+// path template, e.g. /pet/{petId:[0-9]+} becomes /pet/{petId}
+func stripVariablePatterns(template string) string {
+	var b strings.Builder
+	depth, skip := 0, false
+	for _, ch := range template {
+		switch {
+		case ch == '{':
+			depth++
+		case ch == '}':
+			depth--
+			if depth == 0 {
+				skip = false
+			}
+		case ch == ':' && depth == 1:
+			skip = true
+		}
+		if !skip {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,248 @@
+package swagvalidator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/miketonks/swag"
+	"github.com/miketonks/swag/swagger"
+)
+
+// SwaggerValidatorHTTP net/http middleware.
+// Requests are matched against the api.Paths templates, e.g. /pet/{petId}, with basePath applied,
+// so it works with any router, including the standard library http.ServeMux.
+func SwaggerValidatorHTTP(api *swagger.API, opts ...Option) func(http.Handler) http.Handler {
+
+	v := NewValidator(api, opts...)
+
+	routes := newRouteTrie(api)
+
+	// This part runs at runtime, with context for individual request
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern, pathParams, found := routes.match(r.Method, r.URL.EscapedPath())
+			if !found {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			v.serveHTTP(w, r, RouteKey(r.Method, pattern), pathParams, next)
+		})
+	}
+}
+
+var rePathParam = regexp.MustCompile(`\{([^}]+)}`)
+
+// pathParamNames returns the names of the wildcards in a path template, e.g. petId for /pet/{petId}
+func pathParamNames(path string) []string {
+	names := []string{}
+	for _, match := range rePathParam.FindAllStringSubmatch(path, -1) {
+		names = append(names, strings.TrimSuffix(match[1], "..."))
+	}
+	return names
+}
+
+// serveHTTP validates r against the endpoint registered under routeKey and calls next, or sends the ErrorResponse.
+// With response or status validation enabled, the response of next is checked like in the gin and echo middlewares.
+func (v *Validator) serveHTTP(w http.ResponseWriter, r *http.Request, routeKey string, pathParams map[string]string, next http.Handler) {
+	result := v.ValidateRequest(r, routeKey, pathParams)
+	if !result.Valid() {
+		errorResponseHTTP(w, *result.Error)
+		return
+	}
+	if !v.options.checksResponses() || result.Endpoint == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	rec := newResponseRecorder(w, v.options.buffersResponses())
+	next.ServeHTTP(rec, r)
+	if !rec.wroteHeader {
+		return
+	}
+
+	resp := v.checkResponse(r, v.options, routeKey, rec.status, w.Header(), rec.body.Bytes())
+	if !rec.buffered {
+		return
+	}
+	if resp == nil {
+		rec.flush()
+		return
+	}
+	resetContentHeaders(w.Header())
+	errorResponseHTTP(w, *resp)
+}
+
+func errorResponseHTTP(w http.ResponseWriter, resp ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(resp.StatusCode)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// pathTrie matches request paths against swagger path templates, one node per path segment.
+// Static segments take precedence over segments mixing parameters and text, e.g. /files/{name}.json,
+// which take precedence over parameters, e.g. /pet/findByStatus over /pet/{petId}.
+type pathTrie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	static map[string]*trieNode
+	// mixed are segments combining parameters and text, e.g. {name}.json
+	mixed []*mixedSegment
+	// param matches any single non empty segment
+	param *trieNode
+	// routes holds the templates ending at this node, by method
+	routes map[string]*trieRoute
+}
+
+// mixedSegment matches a segment template with parameters and text, the parameter values are its submatches
+type mixedSegment struct {
+	template string
+	re       *regexp.Regexp
+	node     *trieNode
+}
+
+type trieRoute struct {
+	// pattern is the colon path of the template, e.g. /pet/:petId
+	pattern string
+	// params are the names of the template parameters, in path order
+	params []string
+}
+
+func newPathTrie() *pathTrie {
+	return &pathTrie{root: &trieNode{}}
+}
+
+// newRouteTrie returns a pathTrie holding the endpoints of api validated by NewValidator.
+// Paths are built like endpointKey, not cleaned like the api.Walk paths, so trailing slashes are kept.
+func newRouteTrie(api *swagger.API) *pathTrie {
+	routes := newPathTrie()
+	api.Walk(func(_ string, e *swagger.Endpoint) {
+		if e.Handler != nil {
+			routes.add(e.Method, endpointPath(api, e))
+		}
+	})
+	return routes
+}
+
+// add registers a swagger path template for method
+func (t *pathTrie) add(method, path string) {
+	n := t.root
+	route := &trieRoute{pattern: swag.ColonPath(path)}
+	for _, segment := range strings.Split(path, "/")[1:] {
+		names := pathParamNames(segment)
+		if len(names) == 1 && segment == "{"+names[0]+"}" {
+			if n.param == nil {
+				n.param = &trieNode{}
+			}
+			route.params = append(route.params, names[0])
+			n = n.param
+			continue
+		}
+		if len(names) > 0 {
+			route.params = append(route.params, names...)
+			n = n.mixedSegment(segment)
+			continue
+		}
+		if n.static == nil {
+			n.static = map[string]*trieNode{}
+		}
+		child, ok := n.static[segment]
+		if !ok {
+			child = &trieNode{}
+			n.static[segment] = child
+		}
+		n = child
+	}
+	if n.routes == nil {
+		n.routes = map[string]*trieRoute{}
+	}
+	n.routes[strings.ToUpper(method)] = route
+}
+
+// mixedSegment returns the child node of a segment template with parameters and text, e.g. {name}.json
+func (n *trieNode) mixedSegment(template string) *trieNode {
+	for _, m := range n.mixed {
+		if m.template == template {
+			return m.node
+		}
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	last := 0
+	for _, loc := range rePathParam.FindAllStringIndex(template, -1) {
+		expr.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		expr.WriteString("(.+?)")
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(template[last:]))
+	expr.WriteString("$")
+
+	m := &mixedSegment{
+		template: template,
+		re:       regexp.MustCompile(expr.String()),
+		node:     &trieNode{},
+	}
+	n.mixed = append(n.mixed, m)
+	return m.node
+}
+
+// match returns the colon path pattern and path parameters of the template matching method and path
+func (t *pathTrie) match(method, path string) (string, map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return "", nil, false
+	}
+	var values []string
+	route := t.root.match(strings.ToUpper(method), strings.Split(path, "/")[1:], &values)
+	if route == nil {
+		return "", nil, false
+	}
+
+	pathParams := map[string]string{}
+	for i, name := range route.params {
+		value, err := url.PathUnescape(values[i])
+		if err != nil {
+			return "", nil, false
+		}
+		pathParams[name] = value
+	}
+	return route.pattern, pathParams, true
+}
+
+// match walks the remaining segments depth first, values collects the segments matched by parameters
+func (n *trieNode) match(method string, segments []string, values *[]string) *trieRoute {
+	if len(segments) == 0 {
+		return n.routes[method]
+	}
+
+	segment, rest := segments[0], segments[1:]
+	if child, ok := n.static[segment]; ok {
+		if route := child.match(method, rest, values); route != nil {
+			return route
+		}
+	}
+	for _, m := range n.mixed {
+		submatches := m.re.FindStringSubmatch(segment)
+		if submatches == nil {
+			continue
+		}
+		*values = append(*values, submatches[1:]...)
+		if route := m.node.match(method, rest, values); route != nil {
+			return route
+		}
+		*values = (*values)[:len(*values)-len(submatches)+1]
+	}
+	if n.param != nil && segment != "" {
+		*values = append(*values, segment)
+		if route := n.param.match(method, rest, values); route != nil {
+			return route
+		}
+		*values = (*values)[:len(*values)-1]
+	}
+	return nil
+}
@@ -1,5 +1,14 @@
 package swagvalidator
 
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"text/template"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
 type (
 	// locale is an interface for defining custom error strings
 	locale interface {
@@ -58,6 +67,9 @@ type (
 		ConditionThen() string
 		ConditionElse() string
 
+		// NumberOutOfRange is used for numbers outside the range of their int32, int64, float or double format
+		NumberOutOfRange() string
+
 		// ErrorFormat
 		ErrorFormat() string
 	}
@@ -325,3 +337,114 @@ func (l CustomLocale) ConditionThen() string {
 func (l CustomLocale) ConditionElse() string {
 	return `Must validate "else" as "i"`
 }
+
+// NumberOutOfRange ...
+func (l CustomLocale) NumberOutOfRange() string {
+	return `Value out of range for {{.format}}`
+}
+
+// errorTemplates caches parsed locale format-strings, shared by all validators
+var errorTemplates sync.Map
+
+// describe renders the description of a validation error with locale l.
+// gojsonschema formats descriptions with its global Locale, so validators render
+// them again from the error type and details instead of overriding the global.
+func describe(l locale, err gojsonschema.ResultError) string {
+	var format string
+	switch err.(type) {
+	case *gojsonschema.FalseError:
+		format = l.False()
+	case *gojsonschema.RequiredError:
+		format = l.Required()
+	case *gojsonschema.InvalidTypeError:
+		format = l.InvalidType()
+	case *gojsonschema.NumberAnyOfError:
+		format = l.NumberAnyOf()
+	case *gojsonschema.NumberOneOfError:
+		format = l.NumberOneOf()
+	case *gojsonschema.NumberAllOfError:
+		format = l.NumberAllOf()
+	case *gojsonschema.NumberNotError:
+		format = l.NumberNot()
+	case *gojsonschema.MissingDependencyError:
+		format = l.MissingDependency()
+	case *gojsonschema.InternalError:
+		format = l.Internal()
+	case *gojsonschema.ConstError:
+		format = l.Const()
+	case *gojsonschema.EnumError:
+		format = l.Enum()
+	case *gojsonschema.ArrayNoAdditionalItemsError:
+		format = l.ArrayNoAdditionalItems()
+	case *gojsonschema.ArrayMinItemsError:
+		format = l.ArrayMinItems()
+	case *gojsonschema.ArrayMaxItemsError:
+		format = l.ArrayMaxItems()
+	case *gojsonschema.ItemsMustBeUniqueError:
+		format = l.Unique()
+	case *gojsonschema.ArrayContainsError:
+		format = l.ArrayContains()
+	case *gojsonschema.ArrayMinPropertiesError:
+		format = l.ArrayMinProperties()
+	case *gojsonschema.ArrayMaxPropertiesError:
+		format = l.ArrayMaxProperties()
+	case *gojsonschema.AdditionalPropertyNotAllowedError:
+		format = l.AdditionalPropertyNotAllowed()
+	case *gojsonschema.InvalidPropertyPatternError:
+		format = l.InvalidPropertyPattern()
+	case *gojsonschema.InvalidPropertyNameError:
+		format = l.InvalidPropertyName()
+	case *gojsonschema.StringLengthGTEError:
+		format = l.StringGTE()
+	case *gojsonschema.StringLengthLTEError:
+		format = l.StringLTE()
+	case *gojsonschema.DoesNotMatchPatternError:
+		format = l.DoesNotMatchPattern()
+	case *gojsonschema.DoesNotMatchFormatError:
+		format = l.DoesNotMatchFormat()
+		if _, ok := numberFormats[fmt.Sprint(err.Details()["format"])]; ok {
+			format = l.NumberOutOfRange()
+		}
+	case *gojsonschema.MultipleOfError:
+		format = l.MultipleOf()
+	case *gojsonschema.NumberGTEError:
+		format = l.NumberGTE()
+	case *gojsonschema.NumberGTError:
+		format = l.NumberGT()
+	case *gojsonschema.NumberLTEError:
+		format = l.NumberLTE()
+	case *gojsonschema.NumberLTError:
+		format = l.NumberLT()
+	case *gojsonschema.ConditionThenError:
+		format = l.ConditionThen()
+	case *gojsonschema.ConditionElseError:
+		format = l.ConditionElse()
+	default:
+		return err.Description()
+	}
+	return formatDescription(format, err.Details())
+}
+
+func formatDescription(format string, details gojsonschema.ErrorDetails) string {
+	var tpl *template.Template
+	if t, ok := errorTemplates.Load(format); ok {
+		tpl = t.(*template.Template)
+	} else {
+		var err error
+		tpl = template.New(format)
+		if gojsonschema.ErrorTemplateFuncs != nil {
+			tpl.Funcs(gojsonschema.ErrorTemplateFuncs)
+		}
+		tpl, err = tpl.Parse(format)
+		if err != nil {
+			return err.Error()
+		}
+		errorTemplates.Store(format, tpl)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, details); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
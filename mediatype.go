@@ -0,0 +1,144 @@
+package swagvalidator
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// checkConsumes rejects requests with a body whose media type is not in consumes.
+// A body without a Content-Type is taken as application/octet-stream, as RFC 7231 allows,
+// and an empty consumes list accepts any media type.
+func checkConsumes(r *http.Request, consumes []string) *ErrorResponse {
+	if len(consumes) == 0 || !hasBody(r) {
+		return nil
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if allowedMediaType(consumes, contentType) {
+		return nil
+	}
+	return &ErrorResponse{
+		StatusCode: http.StatusUnsupportedMediaType,
+		Message:    "Unsupported media type",
+		Details: map[string]string{
+			"Content-Type": "Must be one of: " + strings.Join(consumes, ", "),
+		},
+	}
+}
+
+// checkAccept rejects requests whose Accept header does not allow any of the media types in produces.
+// A missing Accept header, or an empty produces list, accepts anything.
+func checkAccept(r *http.Request, produces []string) *ErrorResponse {
+	accept := r.Header.Get("Accept")
+	if len(produces) == 0 || strings.TrimSpace(accept) == "" {
+		return nil
+	}
+
+	ranges := parseAccept(accept)
+	for _, p := range produces {
+		if acceptQuality(ranges, strings.ToLower(mediaType(p))) > 0 {
+			return nil
+		}
+	}
+	return &ErrorResponse{
+		StatusCode: http.StatusNotAcceptable,
+		Message:    "Not acceptable",
+		Details: map[string]string{
+			"Accept": "Must accept one of: " + strings.Join(produces, ", "),
+		},
+	}
+}
+
+type acceptRange struct {
+	mediaRange string
+	q          float64
+}
+
+// parseAccept parses the media ranges and their q-values of an Accept header, e.g. `text/html, application/*;q=0.5`
+func parseAccept(accept string) []acceptRange {
+	ranges := []acceptRange{}
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		ar := acceptRange{
+			mediaRange: strings.ToLower(strings.TrimSpace(params[0])),
+			q:          1,
+		}
+		if ar.mediaRange == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(strings.TrimSpace(kv[0])) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					ar.q = q
+				}
+			}
+		}
+		ranges = append(ranges, ar)
+	}
+	return ranges
+}
+
+// acceptQuality returns the q-value given to mt by the most specific matching range, 0 if none matches.
+// Wildcards in mt, e.g. a produces list of */*, match any range they overlap.
+func acceptQuality(ranges []acceptRange, mt string) float64 {
+	q, specificity := 0.0, -1
+	for _, ar := range ranges {
+		if !mediaTypeMatches(ar.mediaRange, mt) && !mediaTypeMatches(mt, ar.mediaRange) {
+			continue
+		}
+		if s := rangeSpecificity(ar.mediaRange); s > specificity {
+			q, specificity = ar.q, s
+		}
+	}
+	return q
+}
+
+func rangeSpecificity(r string) int {
+	if r == "*/*" || r == "*" {
+		return 0
+	}
+	if _, sub := splitMediaType(r); sub == "*" {
+		return 1
+	}
+	return 2
+}
+
+// allowedMediaType returns true if the media type of contentType is in one of the allowed media ranges
+func allowedMediaType(allowed []string, contentType string) bool {
+	mt := strings.ToLower(mediaType(contentType))
+	for _, a := range allowed {
+		if mediaTypeMatches(strings.ToLower(mediaType(a)), mt) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaTypeMatches returns true if the media type mt is in the media range r, e.g. text/* or */*
+func mediaTypeMatches(r, mt string) bool {
+	if r == "*/*" || r == "*" || r == mt {
+		return true
+	}
+	rType, rSub := splitMediaType(r)
+	mType, _ := splitMediaType(mt)
+	return rSub == "*" && rType == mType
+}
+
+// isJSONMediaType returns true for application/json and structured syntax JSON types, e.g. application/problem+json
+func isJSONMediaType(contentType string) bool {
+	mt := strings.ToLower(mediaType(contentType))
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+func splitMediaType(mt string) (string, string) {
+	i := strings.IndexByte(mt, '/')
+	if i < 0 {
+		return mt, ""
+	}
+	return mt[:i], mt[i+1:]
+}
@@ -0,0 +1,110 @@
+package swagvalidator
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/miketonks/swag"
+	"github.com/miketonks/swag/swagger"
+)
+
+// FileLimits restricts the files uploaded to a multipart file parameter
+type FileLimits struct {
+	// MaxSize is the maximum size of each file in bytes, 0 for no limit
+	MaxSize int64
+	// MaxFiles is the maximum number of files, 0 for no limit
+	MaxFiles int
+	// MIMETypes are the allowed media types or ranges, e.g. image/*, empty for any type.
+	// Both the Content-Type declared for the part and the type sniffed from its content must be allowed.
+	MIMETypes []string
+}
+
+// SetFileLimits sets the limits of the multipart file parameter name of the endpoint method path, e.g. "POST", "/api/upload", "upfile".
+// The path includes the basePath and is either a swagger path template or a colon path.
+// The parameter must be declared as a formData parameter of type file.
+func SetFileLimits(method, path, name string, limits FileLimits) Option {
+	return func(o *Options) {
+		if o.FileLimits == nil {
+			o.FileLimits = map[string]map[string]FileLimits{}
+		}
+		key := RouteKey(method, swag.ColonPath(path))
+		if o.FileLimits[key] == nil {
+			o.FileLimits[key] = map[string]FileLimits{}
+		}
+		o.FileLimits[key][name] = limits
+	}
+}
+
+// endpointFileLimits returns the limits set in limits for the file parameters of e
+func endpointFileLimits(e *swagger.Endpoint, limits map[string]FileLimits) map[string]FileLimits {
+	fileLimits := map[string]FileLimits{}
+	for _, p := range e.Parameters {
+		if l, found := limits[p.Name]; found && p.In == "formData" && p.Type == "file" {
+			fileLimits[p.Name] = l
+		}
+	}
+	return fileLimits
+}
+
+// validateFiles checks the files of a parsed multipart request against the limits of the endpoint,
+// the details are keyed by field name
+func (v *Validator) validateFiles(r *http.Request, ve *validatorEndpoint) map[string]string {
+	details := map[string]string{}
+	if r.MultipartForm == nil || len(ve.fileLimits) == 0 {
+		return details
+	}
+	for name, files := range r.MultipartForm.File {
+		limits, found := ve.fileLimits[name]
+		if !found {
+			continue
+		}
+		if err := checkFiles(files, limits); err != "" {
+			details[name] = err
+		}
+	}
+	return details
+}
+
+func checkFiles(files []*multipart.FileHeader, limits FileLimits) string {
+	if limits.MaxFiles > 0 && len(files) > limits.MaxFiles {
+		return fmt.Sprintf("At most %d files allowed", limits.MaxFiles)
+	}
+	for _, fh := range files {
+		if limits.MaxSize > 0 && fh.Size > limits.MaxSize {
+			return fmt.Sprintf("File %s exceeds the maximum size of %d bytes", fh.Filename, limits.MaxSize)
+		}
+		if len(limits.MIMETypes) == 0 {
+			continue
+		}
+		if declared := fh.Header.Get("Content-Type"); declared != "" && !allowedMediaType(limits.MIMETypes, declared) {
+			return fmt.Sprintf("File %s has type %s, must be one of: %s", fh.Filename, mediaType(declared), strings.Join(limits.MIMETypes, ", "))
+		}
+		sniffed, err := sniffContentType(fh)
+		if err != nil {
+			return fmt.Sprintf("Failed to read file %s", fh.Filename)
+		}
+		if !allowedMediaType(limits.MIMETypes, sniffed) {
+			return fmt.Sprintf("File %s has content of type %s, must be one of: %s", fh.Filename, mediaType(sniffed), strings.Join(limits.MIMETypes, ", "))
+		}
+	}
+	return ""
+}
+
+// sniffContentType detects the content type of an uploaded file from its first 512 bytes
+func sniffContentType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
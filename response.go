@@ -0,0 +1,427 @@
+package swagvalidator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/miketonks/swag/swagger"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ResponseValidation sets if and how the middlewares validate responses
+type ResponseValidation int
+
+const (
+	// ResponseValidationOff does not validate responses, the default
+	ResponseValidationOff ResponseValidation = iota
+	// ResponseValidationReport sends responses unchanged and only reports violations to the ResponseErrorHandler
+	ResponseValidationReport
+	// ResponseValidationStrict buffers responses and replaces the ones that violate the spec with a 500 ErrorResponse
+	ResponseValidationStrict
+)
+
+// SetResponseValidation enables validation of response bodies against the documented endpoint responses
+func SetResponseValidation(mode ResponseValidation) Option {
+	return func(o *Options) {
+		o.ResponseValidation = mode
+	}
+}
+
+// SetResponseErrorHandler sets a function called with every response validation error, in report and strict mode
+func SetResponseErrorHandler(h func(r *http.Request, resp ErrorResponse)) Option {
+	return func(o *Options) {
+		o.ResponseErrorHandler = h
+	}
+}
+
+// SetStatusValidation enables checking that response status codes are documented by the endpoint, or that it has a default response
+func SetStatusValidation(mode ResponseValidation) Option {
+	return func(o *Options) {
+		o.StatusValidation = mode
+	}
+}
+
+// SetStatusCounts collects the undocumented status codes found by status validation in counts
+func SetStatusCounts(counts *StatusCounts) Option {
+	return func(o *Options) {
+		o.StatusCounts = counts
+	}
+}
+
+// checksResponses returns true if any response validation is enabled
+func (o *Options) checksResponses() bool {
+	return o.ResponseValidation != ResponseValidationOff || o.StatusValidation != ResponseValidationOff
+}
+
+// buffersResponses returns true if responses may have to be replaced after validation
+func (o *Options) buffersResponses() bool {
+	return o.ResponseValidation == ResponseValidationStrict || o.StatusValidation == ResponseValidationStrict
+}
+
+// StatusCounts counts the undocumented response status codes sent by each endpoint.
+// It is safe for concurrent use.
+type StatusCounts struct {
+	mu     sync.Mutex
+	counts map[string]map[int]int
+}
+
+// NewStatusCounts returns empty StatusCounts
+func NewStatusCounts() *StatusCounts {
+	return &StatusCounts{counts: map[string]map[int]int{}}
+}
+
+// Add counts status for the endpoint registered under routeKey
+func (s *StatusCounts) Add(routeKey string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts[routeKey] == nil {
+		s.counts[routeKey] = map[int]int{}
+	}
+	s.counts[routeKey][status]++
+}
+
+// Counts returns a copy of the counts, by route key and status code
+func (s *StatusCounts) Counts() map[string]map[int]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]map[int]int, len(s.counts))
+	for key, statuses := range s.counts {
+		counts[key] = make(map[int]int, len(statuses))
+		for status, n := range statuses {
+			counts[key][status] = n
+		}
+	}
+	return counts
+}
+
+type responseSchema struct {
+	response swagger.Response
+	// schema of the response body, nil if the response does not document one
+	schema *gojsonschema.Schema
+	err    error
+}
+
+// ValidateResponse validates a response of the endpoint registered under routeKey, against the
+// response documented for status, or the default response. The documented headers must be present in
+// header and coerce to their declared type. Responses without a documented schema, or with a Content-Type
+// that is not JSON, e.g. application/xml, have their body accepted as is.
+func (v *Validator) ValidateResponse(routeKey string, status int, header http.Header, body []byte) *Result {
+	ve, found := v.endpoints[routeKey]
+	if !found {
+		return &Result{}
+	}
+	result := &Result{Endpoint: ve.endpoint}
+
+	rs, found := ve.responses[strconv.Itoa(status)]
+	if !found {
+		rs, found = ve.responses["default"]
+	}
+	if !found {
+		return result
+	}
+	if rs.err != nil {
+		result.Error = &ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "swagger document " + rs.err.Error(),
+		}
+		return result
+	}
+
+	details := v.validateResponseHeaders(rs.response.Headers, header)
+	// only JSON bodies are validated, a response without Content-Type is taken as JSON
+	if contentType := header.Get("Content-Type"); rs.schema != nil && (contentType == "" || isJSONMediaType(contentType)) {
+		var document interface{}
+		if err := decodeJSON(body, &document); err != nil {
+			details["body"] = "Invalid JSON format"
+		} else {
+			res, err := rs.schema.Validate(gojsonschema.NewGoLoader(document))
+			if err != nil {
+				result.Error = &ErrorResponse{
+					StatusCode: http.StatusInternalServerError,
+					Message:    "swagger document " + err.Error(),
+				}
+				return result
+			}
+			for field, description := range v.flattenErrors(res.Errors()) {
+				// errors on the body itself, e.g. an object instead of an array
+				if field == "(root)" {
+					field = "body"
+				}
+				details[field] = description
+			}
+		}
+	}
+
+	if len(details) > 0 {
+		result.Error = responseError(details)
+	}
+	return result
+}
+
+// validateResponseHeaders checks the documented headers are present, with values of the declared type
+func (v *Validator) validateResponseHeaders(documented map[string]swagger.Header, header http.Header) map[string]string {
+	details := map[string]string{}
+	for name, h := range documented {
+		value := header.Get(name)
+		if value == "" {
+			details[name] = formatDescription(v.locale.Required(), gojsonschema.ErrorDetails{"property": name})
+			continue
+		}
+		if h.Type == "string" || h.Type == "" {
+			continue
+		}
+		// coerce leaves values it cannot convert to the declared type as strings,
+		// and returns numbers out of the range of their Go type as json.Number
+		switch coerced := coerce(value, h.Type, h.Format).(type) {
+		case string:
+			details[name] = formatDescription(v.locale.InvalidType(), gojsonschema.ErrorDetails{
+				"expected": h.Type,
+				"given":    "string",
+			})
+		case json.Number:
+			if !inFormatRange(coerced, h.Format) {
+				details[name] = formatDescription(v.locale.NumberOutOfRange(), gojsonschema.ErrorDetails{
+					"format": h.Format,
+				})
+			}
+		}
+	}
+	return details
+}
+
+// DocumentedStatus returns true if the endpoint registered under routeKey documents status, or has a default response.
+// Routes that are not in the swagger spec document any status.
+func (v *Validator) DocumentedStatus(routeKey string, status int) bool {
+	ve, found := v.endpoints[routeKey]
+	if !found {
+		return true
+	}
+	if _, found := ve.responses[strconv.Itoa(status)]; found {
+		return true
+	}
+	_, found = ve.responses["default"]
+	return found
+}
+
+// checkResponse runs the response validations enabled in o and reports their errors.
+// It returns the error to send instead of the response, in strict mode.
+func (v *Validator) checkResponse(r *http.Request, o *Options, routeKey string, status int, header http.Header, body []byte) *ErrorResponse {
+	if resp, documented := v.checkStatus(r, o, routeKey, status); !documented {
+		return resp
+	}
+
+	// HEAD responses have no body to validate
+	if o.ResponseValidation == ResponseValidationOff || r.Method == http.MethodHead {
+		return nil
+	}
+	result := v.ValidateResponse(routeKey, status, header, body)
+	if result.Valid() {
+		return nil
+	}
+	reportResponseError(r, o, *result.Error)
+	if o.ResponseValidation == ResponseValidationStrict {
+		return result.Error
+	}
+	return nil
+}
+
+// checkStatus counts and reports status if status validation is enabled in o and the endpoint does not document it.
+// It returns false for undocumented statuses, with the error to send instead of the response in strict mode.
+func (v *Validator) checkStatus(r *http.Request, o *Options, routeKey string, status int) (*ErrorResponse, bool) {
+	if o.StatusValidation == ResponseValidationOff || v.DocumentedStatus(routeKey, status) {
+		return nil, true
+	}
+	if o.StatusCounts != nil {
+		o.StatusCounts.Add(routeKey, status)
+	}
+	resp := responseError(map[string]string{
+		"status": fmt.Sprintf("Undocumented response status %d", status),
+	})
+	reportResponseError(r, o, *resp)
+	if o.StatusValidation == ResponseValidationStrict {
+		return resp, false
+	}
+	return nil, false
+}
+
+// resetContentHeaders removes the Content-* headers set by the handler for a response replaced by an error,
+// e.g. its Content-Type and Content-Encoding. Other headers, e.g. set by CORS middlewares, are kept.
+func resetContentHeaders(header http.Header) {
+	for k := range header {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), "Content-") {
+			delete(header, k)
+		}
+	}
+}
+
+func responseError(details map[string]string) *ErrorResponse {
+	return &ErrorResponse{
+		StatusCode: http.StatusInternalServerError,
+		Message:    "Response validation error",
+		Details:    details,
+	}
+}
+
+func reportResponseError(r *http.Request, o *Options, resp ErrorResponse) {
+	if o.ResponseErrorHandler != nil {
+		o.ResponseErrorHandler(r, resp)
+	}
+}
+
+// ginResponseRecorder captures the status and body written by gin handlers.
+// When buffered, nothing is sent until flush is called, so the response can still be replaced.
+type ginResponseRecorder struct {
+	gin.ResponseWriter
+	buffered bool
+	status   int
+	written  bool
+	body     bytes.Buffer
+}
+
+func newGinResponseRecorder(w gin.ResponseWriter, buffered bool) *ginResponseRecorder {
+	return &ginResponseRecorder{
+		ResponseWriter: w,
+		buffered:       buffered,
+		status:         http.StatusOK,
+	}
+}
+
+func (w *ginResponseRecorder) WriteHeader(code int) {
+	if !w.buffered {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *ginResponseRecorder) WriteHeaderNow() {
+	if !w.buffered {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.written = true
+}
+
+func (w *ginResponseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	if !w.buffered {
+		return w.ResponseWriter.Write(b)
+	}
+	w.written = true
+	return len(b), nil
+}
+
+func (w *ginResponseRecorder) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *ginResponseRecorder) Status() int {
+	if !w.buffered {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *ginResponseRecorder) Size() int {
+	if !w.buffered {
+		return w.ResponseWriter.Size()
+	}
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *ginResponseRecorder) Written() bool {
+	if !w.buffered {
+		return w.ResponseWriter.Written()
+	}
+	return w.written
+}
+
+func (w *ginResponseRecorder) Flush() {
+	if !w.buffered {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// flush sends the buffered response
+func (w *ginResponseRecorder) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// responseRecorder captures the status and body written to an http.ResponseWriter.
+// When buffered, nothing is sent until flush is called, so the response can still be replaced.
+type responseRecorder struct {
+	http.ResponseWriter
+	buffered    bool
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newResponseRecorder(w http.ResponseWriter, buffered bool) *responseRecorder {
+	return &responseRecorder{
+		ResponseWriter: w,
+		buffered:       buffered,
+		status:         http.StatusOK,
+	}
+}
+
+func (w *responseRecorder) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+	if !w.buffered {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(b)
+	if !w.buffered {
+		return w.ResponseWriter.Write(b)
+	}
+	return len(b), nil
+}
+
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.buffered {
+		f.Flush()
+	}
+}
+
+// Hijack lets handlers take over the connection, e.g. for websockets, if the wrapped writer supports it
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("swagvalidator: %T does not support hijacking", w.ResponseWriter)
+	}
+	return h.Hijack()
+}
+
+// flush sends the buffered response
+func (w *responseRecorder) flush() {
+	if !w.wroteHeader {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
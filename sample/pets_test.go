@@ -138,6 +138,7 @@ func postStr(t *testing.T, router *gin.Engine, path, data string) *httptest.Resp
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
 	return resp
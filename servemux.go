@@ -0,0 +1,107 @@
+//go:build go1.22
+
+package swagvalidator
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/miketonks/swag/swagger"
+)
+
+// RegisterServeMux registers the handler of every endpoint in api on mux, wrapped in the validator.
+// Routes use the Go 1.22 method and pattern syntax, e.g. "GET /api/pet/{petId}", and path params
+// are read with r.PathValue. Parameter names that are not valid Go identifiers, e.g. {pet-id}, are registered
+// with their invalid characters replaced by underscores, so handlers read them with r.PathValue("pet_id").
+// Paths ending in a slash, including the root path /, only match that exact path, e.g. "GET /api/{$}".
+// Endpoint handlers must be an http.Handler or func(http.ResponseWriter, *http.Request), and path segments
+// mixing parameters and text, e.g. /files/{name}.json, are not supported by ServeMux: both panic.
+// The patterns need a main module declaring go 1.22 or later, or GODEBUG=httpmuxgo121=0.
+func RegisterServeMux(mux *http.ServeMux, api *swagger.API, opts ...Option) {
+
+	v := NewValidator(api, opts...)
+
+	api.Walk(func(_ string, e *swagger.Endpoint) {
+		if e.Handler == nil {
+			return
+		}
+		next := endpointHTTPHandler(e)
+		key := endpointKey(api, e)
+		pattern, names := muxPattern(endpointPath(api, e))
+
+		mux.Handle(e.Method+" "+pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pathParams := map[string]string{}
+			for name, muxName := range names {
+				pathParams[name] = r.PathValue(muxName)
+			}
+
+			v.serveHTTP(w, r, key, pathParams, next)
+		}))
+	})
+}
+
+func endpointHTTPHandler(e *swagger.Endpoint) http.Handler {
+	switch h := e.Handler.(type) {
+	case http.Handler:
+		return h
+	case func(w http.ResponseWriter, r *http.Request):
+		return http.HandlerFunc(h)
+	default:
+		panic(fmt.Sprintf("swagvalidator: handler of %s %s is not an http.Handler: %T", e.Method, e.Path, e.Handler))
+	}
+}
+
+// muxPattern returns the ServeMux pattern of a path template, with its wildcards renamed to valid Go identifiers,
+// and the ServeMux name of every swagger parameter name. A trailing slash is matched exactly with {$}.
+func muxPattern(path string) (string, map[string]string) {
+	for _, segment := range strings.Split(path, "/") {
+		if names := pathParamNames(segment); len(names) > 0 && segment != "{"+names[0]+"}" {
+			panic(fmt.Sprintf("swagvalidator: segment %s of path %s mixes parameters and text, which ServeMux does not support", segment, path))
+		}
+	}
+
+	names := map[string]string{}
+	used := map[string]bool{}
+	pattern := rePathParam.ReplaceAllStringFunc(path, func(wildcard string) string {
+		name := wildcard[1 : len(wildcard)-1]
+		suffix := ""
+		if strings.HasSuffix(name, "...") {
+			name, suffix = strings.TrimSuffix(name, "..."), "..."
+		}
+		muxName := identifier(name)
+		for i := 2; used[muxName]; i++ {
+			muxName = fmt.Sprintf("%s_%d", identifier(name), i)
+		}
+		used[muxName] = true
+		names[name] = muxName
+		return "{" + muxName + suffix + "}"
+	})
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "{$}"
+	}
+	return pattern, names
+}
+
+// identifier replaces the characters of name that are not allowed in a Go identifier with underscores
+func identifier(name string) string {
+	var b strings.Builder
+	for i, ch := range name {
+		switch {
+		case ch == '_' || unicode.IsLetter(ch):
+			b.WriteRune(ch)
+		case unicode.IsDigit(ch):
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(ch)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
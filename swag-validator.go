@@ -1,13 +1,10 @@
 package swagvalidator
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"reflect"
-	"runtime"
 	"strconv"
 	"strings"
 
@@ -15,19 +12,46 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/miketonks/swag"
 	"github.com/miketonks/swag/swagger"
-	"github.com/xeipuuv/gojsonschema"
 )
 
 // MaxMemory ...
 const MaxMemory = 1 * 1024 * 1024
 
+// Option configures the validator middlewares
+type Option func(*Options)
+
+// Options shared by all the middlewares. The request validation options apply to every middleware.
+// ReturnErrors applies to the gin, echo and fiber middlewares, which can hand errors to the framework.
+// The response and status validation options apply to every middleware but fiber.
+type Options struct {
+	ReturnErrors     bool
+	AcceptValidation bool
+	// FileLimits of multipart file parameters, by route key and name
+	FileLimits map[string]map[string]FileLimits
+	// CollectionFormats of array parameters, by route key and name
+	CollectionFormats map[string]map[string]string
+	// MaxBodySize of request bodies in bytes, 0 for no limit
+	MaxBodySize int64
+	// EndpointMaxBodySizes override MaxBodySize, by route key
+	EndpointMaxBodySizes map[string]int64
+
+	ResponseValidation   ResponseValidation
+	ResponseErrorHandler func(r *http.Request, resp ErrorResponse)
+	StatusValidation     ResponseValidation
+	StatusCounts         *StatusCounts
+}
+
 // EchoOption ...
-type EchoOption func(*EchoOptions)
+type EchoOption = Option
 
 // EchoOptions ...
-type EchoOptions struct {
-	ReturnErrors bool
-}
+type EchoOptions = Options
+
+// GinOption ...
+type GinOption = Option
+
+// GinOptions ...
+type GinOptions = Options
 
 // SetEchoReturnErrors ...
 func SetEchoReturnErrors(b bool) EchoOption {
@@ -36,6 +60,65 @@ func SetEchoReturnErrors(b bool) EchoOption {
 	}
 }
 
+// SetGinReturnErrors makes the gin middleware add an ErrorResponse to the context with c.Error and abort,
+// instead of sending a JSON response. This allows a gin error handling middleware to render it.
+func SetGinReturnErrors(b bool) GinOption {
+	return func(o *GinOptions) {
+		o.ReturnErrors = b
+	}
+}
+
+// SetAcceptValidation rejects requests with a 406 ErrorResponse when their Accept header
+// does not allow any of the media types the endpoint produces
+func SetAcceptValidation(b bool) Option {
+	return func(o *Options) {
+		o.AcceptValidation = b
+	}
+}
+
+// SetCollectionFormat sets the swagger collectionFormat of the array parameter name of the endpoint method path,
+// e.g. "GET", "/api/pet/findByStatus", "status": csv, ssv, tsv, pipes or multi. The path includes the basePath and
+// is either a swagger path template or a colon path. Without it, arrays are read from repeated parameters or comma
+// separated values.
+func SetCollectionFormat(method, path, name, format string) Option {
+	return func(o *Options) {
+		if o.CollectionFormats == nil {
+			o.CollectionFormats = map[string]map[string]string{}
+		}
+		key := RouteKey(method, swag.ColonPath(path))
+		if o.CollectionFormats[key] == nil {
+			o.CollectionFormats[key] = map[string]string{}
+		}
+		o.CollectionFormats[key][name] = format
+	}
+}
+
+// SetMaxBodySize rejects requests with a body larger than n bytes with a 413 ErrorResponse
+func SetMaxBodySize(n int64) Option {
+	return func(o *Options) {
+		o.MaxBodySize = n
+	}
+}
+
+// SetEndpointMaxBodySize overrides the maximum body size of the endpoint method path, e.g. "POST", "/api/upload".
+// The path includes the basePath and is either a swagger path template or a colon path.
+func SetEndpointMaxBodySize(method, path string, n int64) Option {
+	return func(o *Options) {
+		if o.EndpointMaxBodySizes == nil {
+			o.EndpointMaxBodySizes = map[string]int64{}
+		}
+		o.EndpointMaxBodySizes[RouteKey(method, swag.ColonPath(path))] = n
+	}
+}
+
+func buildOptions(opts []Option) *Options {
+	options := &Options{}
+	for _, o := range opts {
+		o(options)
+	}
+	return options
+}
+
 // RequestSchema ...
 type RequestSchema struct {
 	Title                string                      `json:"title"`
@@ -43,7 +126,7 @@ type RequestSchema struct {
 	Summary              string                      `json:"summary"`
 	Properties           map[string]interface{}      `json:"properties"`
 	Required             []string                    `json:"required"`
-	Definitions          map[string]SchemaDefinition `json:"definitions"`
+	Definitions          map[string]SchemaDefinition `json:"definitions,omitempty"`
 	AdditionalProperties bool                        `json:"additionalProperties"`
 }
 
@@ -115,18 +198,27 @@ type SchemaProperty struct {
 type ErrorResponse struct {
 	StatusCode int               `json:"-"`
 	Message    string            `json:"message"`
-	Details    map[string]string `json:"details"`
+	Details    map[string]string `json:"details,omitempty"`
 }
 
 func (e ErrorResponse) Error() string {
 	return e.Message
 }
 
-func loadValueForKey(properties map[string]interface{}, key string, values []string) interface{} {
+// collectionFormats are the separators of the swagger 2.0 collectionFormat values, multi uses repeated parameters instead
+var collectionFormats = map[string]string{
+	"csv":   ",",
+	"ssv":   " ",
+	"tsv":   "\t",
+	"pipes": "|",
+}
+
+func loadValueForKey(properties map[string]interface{}, key string, values []string) (interface{}, error) {
 	valueType := ""
 	valueFormat := ""
 	elemType := ""
 	elemFormat := ""
+	collectionFormat := ""
 	propI, found := properties[key]
 	if found {
 		prop := propI.(map[string]interface{})
@@ -146,325 +238,143 @@ func loadValueForKey(properties map[string]interface{}, key string, values []str
 				elemFormat = f.(string)
 			}
 		}
+		collectionFormat, _ = prop["collectionFormat"].(string)
 	}
 
 	// if parameter isn't an array and we didn't receive multiple values, pass it as a normal value
 	if len(values) == 1 && valueType != "array" {
-		return coerce(values[0], valueType, valueFormat)
+		return coerce(values[0], valueType, valueFormat), nil
 	}
 
-	// if we received multiple values, use them as the elements; otherwise, split the value we got
 	var items []string
-	if len(values) > 1 {
+	switch collectionFormat {
+	case "":
+		// if we received multiple values, use them as the elements; otherwise, split the value we got
+		if len(values) > 1 {
+			items = values
+		} else {
+			items = strings.Split(values[0], ",")
+		}
+	case "multi":
 		items = values
-	} else {
-		items = strings.Split(values[0], ",")
+	default:
+		sep, ok := collectionFormats[collectionFormat]
+		if !ok {
+			return nil, fmt.Errorf("Unknown collection format %s", collectionFormat)
+		}
+		if len(values) > 1 {
+			return nil, fmt.Errorf("Invalid collection format. Expected: a single %s separated value, given: %d values", collectionFormat, len(values))
+		}
+		items = strings.Split(values[0], sep)
 	}
 
 	result := []interface{}{}
 	for _, item := range items {
 		result = append(result, coerce(strings.TrimSpace(item), elemType, elemFormat))
 	}
-	return result
+	return result, nil
 }
 
 // SwaggerValidator Gin middleware
-func SwaggerValidator(api *swagger.API) gin.HandlerFunc {
-
-	apiMap := map[string]gojsonschema.JSONLoader{}
-	for _, p := range api.Paths {
-		for _, e := range []*swagger.Endpoint{
-			p.Delete,
-			p.Get,
-			p.Post,
-			p.Put,
-			p.Patch,
-			p.Head,
-			p.Options,
-			p.Trace,
-			p.Connect} {
-			if e != nil && e.Handler != nil {
-				schema := buildRequestSchema(e)
-				schema.Definitions = buildSchemaDefinitions(api)
-				schemaLoader := gojsonschema.NewGoLoader(schema)
-
-				handler := nameOfFunction(e.Handler)
-				apiMap[handler] = schemaLoader
-			}
-		}
-	}
+func SwaggerValidator(api *swagger.API, opts ...GinOption) gin.HandlerFunc {
+
+	v := NewValidator(api, opts...)
+	options := v.options
 
 	// This part runs at runtime, with context for individual request
 	return func(c *gin.Context) {
-		schemaLoader, found := apiMap[c.HandlerName()]
-		if !found {
-			c.Next()
-			return
+		pathParams := map[string]string{}
+		for _, p := range c.Params {
+			pathParams[p.Key] = p.Value
 		}
-		ref, _ := schemaLoader.LoadJSON()
-		properties, _ := ref.(map[string]interface{})["properties"].(map[string]interface{})
-
-		document := map[string]interface{}{}
 
-		for _, p := range c.Params {
-			document[p.Key] = loadValueForKey(properties, p.Key, []string{p.Value})
+		key := RouteKey(c.Request.Method, c.FullPath())
+		result := v.ValidateRequest(c.Request, key, pathParams)
+		if !result.Valid() {
+			errorResponseGin(c, options, *result.Error)
+			return
 		}
-		for k, v := range c.Request.URL.Query() {
-			document[k] = loadValueForKey(properties, k, v)
+		if !options.checksResponses() || result.Endpoint == nil {
+			c.Next()
+			return
 		}
 
-		// For muiltipart form, handle params and file uploads
-		if c.ContentType() == "multipart/form-data" {
-			r := c.Request
-			r.ParseMultipartForm(MaxMemory)
+		rec := newGinResponseRecorder(c.Writer, options.buffersResponses())
+		c.Writer = rec
+		c.Next()
+		c.Writer = rec.ResponseWriter
 
-			for k, v := range c.Request.PostForm {
-				document[k] = coerce(v[0], "", "")
-			}
-			if r.MultipartForm != nil && r.MultipartForm.File != nil {
-				for k := range r.MultipartForm.File {
-					document[k] = "x"
-				}
-			}
-		} else if c.ContentType() == "application/x-www-form-urlencoded" {
-			r := c.Request
-			r.ParseForm()
-
-			body := map[string]interface{}{}
-			for k, v := range c.Request.PostForm {
-				body[k] = coerce(v[0], "", "")
-			}
-			document["body"] = body
-		} else if c.Request.ContentLength > 0 {
-			// For all other types parse body as json, if possible
-
-			// read the response body to a variable
-			var body interface{}
-			b, err := ioutil.ReadAll(c.Request.Body)
-			if err != nil {
-				c.AbortWithStatusJSON(
-					http.StatusBadRequest,
-					gin.H{
-						"message": "Validation error",
-						"details": map[string]string{
-							"body": "Failed to read request body",
-						},
-					},
-				)
-				return
-			}
-			err = json.Unmarshal(b, &body)
-			// TODO Consider different error cases: Empty Body, Invalid JSON, Form Data
-			if err != nil {
-				c.AbortWithStatusJSON(
-					http.StatusBadRequest,
-					gin.H{
-						"message": "Validation error",
-						"details": map[string]string{
-							"body": "Invalid JSON format",
-						},
-					},
-				)
-				return
-			}
-			document["body"] = body
-
-			//reset the response body to the original unread state
-			c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(b))
+		resp := v.checkResponse(c.Request, options, key, rec.Status(), c.Writer.Header(), rec.body.Bytes())
+		if !rec.buffered {
+			return
 		}
-
-		gojsonschema.Locale = CustomLocale{}
-
-		documentLoader := gojsonschema.NewGoLoader(document)
-		result, err := gojsonschema.Validate(schemaLoader, documentLoader)
-
-		if err != nil {
-			// fmt.Printf("ERROR: %s\n", err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"message": "swagger document " + err.Error(),
-			})
-
-		} else if result.Valid() {
-			// fmt.Printf("The document is valid\n")
-			c.Next()
-		} else {
-			// fmt.Printf("The document is not valid. see errors :\n")
-			errors := map[string]string{}
-			for _, err := range result.Errors() {
-				description := err.Description()
-				details := err.Details()
-
-				field := details["field"].(string)
-				if val, ok := details["property"]; ok {
-					field += "." + val.(string)
-				}
-				field = strings.TrimPrefix(field, "body.")
-				field = strings.TrimPrefix(field, "(root).")
-				errors[field] = description
-			}
-			// fmt.Printf("The document is not valid. see errors : %+v\n", errors)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"message": "Validation error",
-				"details": errors,
-			})
+		if resp == nil {
+			rec.flush()
+			return
 		}
+		resetContentHeaders(c.Writer.Header())
+		errorResponseGin(c, options, *resp)
 	}
 }
 
 // SwaggerValidatorEcho middleware
 func SwaggerValidatorEcho(api *swagger.API, opts ...EchoOption) echo.MiddlewareFunc {
 
-	options := &EchoOptions{}
-	for _, o := range opts {
-		o(options)
-	}
-
-	basePath := strings.TrimRight(api.BasePath, "/")
-
-	apiMap := map[string]gojsonschema.JSONLoader{}
-	for _, p := range api.Paths {
-		for _, e := range []*swagger.Endpoint{
-			p.Delete,
-			p.Get,
-			p.Post,
-			p.Put,
-			p.Patch,
-			p.Head,
-			p.Options,
-			p.Trace,
-			p.Connect} {
-			if e != nil && e.Handler != nil {
-				schema := buildRequestSchema(e)
-				schema.Definitions = buildSchemaDefinitions(api)
-				schemaLoader := gojsonschema.NewGoLoader(schema)
-
-				key := e.Method + basePath + swag.ColonPath(e.Path)
-				apiMap[key] = schemaLoader
-			}
-		}
-	}
+	v := NewValidator(api, opts...)
+	options := v.options
 
 	// This part runs at runtime, with context for individual request
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			key := c.Request().Method + c.Path()
-			schemaLoader, found := apiMap[key]
-			if !found {
-				return next(c)
-			}
-			ref, _ := schemaLoader.LoadJSON()
-			properties, _ := ref.(map[string]interface{})["properties"].(map[string]interface{})
-
-			document := map[string]interface{}{}
-
+			pathParams := map[string]string{}
 			for _, key := range c.ParamNames() {
-				document[key] = loadValueForKey(properties, key, []string{c.Param(key)})
-			}
-			for k, v := range c.Request().URL.Query() {
-				document[k] = loadValueForKey(properties, k, v)
+				pathParams[key] = c.Param(key)
 			}
 
-			// Strip any options off the content type, e.g. `; charset=UTF-8`
-			contentType := c.Request().Header.Get(echo.HeaderContentType)
-			for i, ch := range contentType {
-				if ch == ' ' || ch == ';' {
-					contentType = contentType[:i]
-					break
-				}
+			key := RouteKey(c.Request().Method, c.Path())
+			result := v.ValidateRequest(c.Request(), key, pathParams)
+			if !result.Valid() {
+				return errorResponse(c, options, *result.Error)
+			}
+			if !options.checksResponses() || result.Endpoint == nil {
+				return next(c)
 			}
 
-			// For muiltipart form, handle params and file uploads
-			if contentType == "multipart/form-data" {
-				r := c.Request()
-				r.ParseMultipartForm(MaxMemory)
+			res := c.Response()
+			rec := newResponseRecorder(res.Writer, options.buffersResponses())
+			res.Writer = rec
+			err := next(c)
+			res.Writer = rec.ResponseWriter
 
-				for k, v := range c.Request().PostForm {
-					document[k] = coerce(v[0], "", "")
-				}
-				if r.MultipartForm != nil && r.MultipartForm.File != nil {
-					for k := range r.MultipartForm.File {
-						document[k] = "x"
-					}
+			if err != nil || !rec.wroteHeader {
+				if rec.buffered {
+					rec.flush()
 				}
-			} else if contentType == "application/x-www-form-urlencoded" {
-				r := c.Request()
-				r.ParseForm()
-
-				body := map[string]interface{}{}
-				for k, v := range c.Request().PostForm {
-					body[k] = coerce(v[0], "", "")
+				// errors returned by the handler are rendered later by the echo HTTPErrorHandler,
+				// so only the status of an echo.HTTPError can be checked, not its body
+				var he *echo.HTTPError
+				if err == nil || rec.wroteHeader || !errors.As(err, &he) {
+					return err
 				}
-				document["body"] = body
-			} else if c.Request().ContentLength > 0 {
-				// For all other types parse body as json, if possible
-
-				// read the response body to a variable
-				var body interface{}
-				b, err := ioutil.ReadAll(c.Request().Body)
-				if err != nil {
-					return errorResponse(c, options, ErrorResponse{
-						StatusCode: http.StatusBadRequest,
-						Message:    "Validation error",
-						Details: map[string]string{
-							"body": "Failed to read request body",
-						},
-					},
-					)
+				if resp, _ := v.checkStatus(c.Request(), options, key, he.Code); resp != nil {
+					return errorResponse(c, options, *resp)
 				}
-				err = json.Unmarshal(b, &body)
-				// TODO Consider different error cases: Empty Body, Invalid JSON, Form Data
-				if err != nil {
-					return errorResponse(c, options, ErrorResponse{
-						StatusCode: http.StatusBadRequest,
-						Message:    "Validation error",
-						Details: map[string]string{
-							"body": "Invalid JSON format",
-						},
-					},
-					)
-				}
-				document["body"] = body
-
-				//reset the response body to the original unread state
-				c.Request().Body = ioutil.NopCloser(bytes.NewBuffer(b))
+				return err
 			}
 
-			gojsonschema.Locale = CustomLocale{}
-
-			documentLoader := gojsonschema.NewGoLoader(document)
-			result, err := gojsonschema.Validate(schemaLoader, documentLoader)
-
-			if err != nil {
-				// fmt.Printf("ERROR: %s\n", err)
-				return c.JSON(http.StatusInternalServerError, echo.Map{
-					"message": "swagger document " + err.Error(),
-				})
-
-			} else if result.Valid() {
-				// fmt.Printf("The document is valid\n")
-				return next(c)
-			} else {
-				// fmt.Printf("The document is not valid. see errors :\n")
-				errors := map[string]string{}
-				for _, err := range result.Errors() {
-					description := err.Description()
-					details := err.Details()
-
-					field := details["field"].(string)
-					if val, ok := details["property"]; ok {
-						field += "." + val.(string)
-					}
-					field = strings.TrimPrefix(field, "body.")
-					field = strings.TrimPrefix(field, "(root).")
-					errors[field] = description
-				}
-				// fmt.Printf("The document is not valid. see errors : %+v\n", errors)
-				return errorResponse(c, options, ErrorResponse{
-					StatusCode: http.StatusBadRequest,
-					Message:    "Validation error",
-					Details:    errors,
-				})
+			resp := v.checkResponse(c.Request(), options, key, rec.status, res.Header(), rec.body.Bytes())
+			if !rec.buffered {
+				return nil
+			}
+			if resp == nil {
+				rec.flush()
+				return nil
 			}
+			// discard the buffered response so the error can be written instead
+			res.Committed = false
+			res.Size = 0
+			resetContentHeaders(res.Header())
+			return errorResponse(c, options, *resp)
 		}
 	}
 }
@@ -476,6 +386,15 @@ func errorResponse(c echo.Context, o *EchoOptions, resp ErrorResponse) error {
 	return c.JSON(resp.StatusCode, resp)
 }
 
+func errorResponseGin(c *gin.Context, o *GinOptions, resp ErrorResponse) {
+	if o.ReturnErrors {
+		c.Error(resp)
+		c.Abort()
+		return
+	}
+	c.AbortWithStatusJSON(resp.StatusCode, resp)
+}
+
 // Data types are defined here: https://swagger.io/specification/#dataTypes
 func coerce(value string, valueType string, valueFormat string) interface{} {
 	switch valueType {
@@ -488,6 +407,9 @@ func coerce(value string, valueType string, valueFormat string) interface{} {
 		if err == nil {
 			return v
 		}
+		if isRangeError(err) {
+			return outOfRange(value)
+		}
 	case "number":
 		bitSize := 32
 		if valueFormat == "double" {
@@ -497,6 +419,9 @@ func coerce(value string, valueType string, valueFormat string) interface{} {
 		if err == nil {
 			return v
 		}
+		if isRangeError(err) {
+			return outOfRange(value)
+		}
 	case "string":
 		if valueFormat == "byte" {
 			return []byte(value)
@@ -514,8 +439,18 @@ func coerce(value string, valueType string, valueFormat string) interface{} {
 	return value
 }
 
-func nameOfFunction(f interface{}) string {
-	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+func isRangeError(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
+
+// outOfRange returns a number too large for its format as a json.Number, so the format checkers
+// report it as out of range, like they do for bodies. Values that are not JSON numbers stay strings.
+func outOfRange(value string) interface{} {
+	if !json.Valid([]byte(value)) {
+		return value
+	}
+	return json.Number(value)
 }
 
 func buildRequestSchema(e *swagger.Endpoint) *RequestSchema {
@@ -0,0 +1,111 @@
+package swagvalidator_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+	swag "github.com/miketonks/swag"
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+
+	sv "github.com/miketonks/swag-validator"
+)
+
+var benchPayload = payload{
+	FormatString:    testUUID,
+	FormatStringArr: []string{testUUID, testUUID},
+	MinLenString:    "123456",
+	EnumString:      "Foo",
+	PatternString:   "test",
+	Minimum:         10,
+	Nested:          &nested{Foo: "bar"},
+	MinItemsArr:     []string{"1", "2"},
+}
+
+func benchAPI(h interface{}) *swagger.API {
+	return swag.New(swag.Endpoints(endpoint.New("POST", "/validate-test/{id}", "Test the validator",
+		endpoint.Handler(h),
+		endpoint.Path("id", "integer", "int64", ""),
+		endpoint.Query("limit", "integer", "", "", false),
+		endpoint.Body(payload{}, "Validation body", true),
+	)))
+}
+
+func BenchmarkValidateRequest(b *testing.B) {
+	v := sv.NewValidator(benchAPI(func() {}))
+	key := sv.RouteKey("POST", "/validate-test/:id")
+	pathParams := map[string]string{"id": "10"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := preparePostRequest("/validate-test/10?limit=5", benchPayload)
+		if result := v.ValidateRequest(req, key, pathParams); !result.Valid() {
+			b.Fatalf("unexpected validation error: %+v", result.Error)
+		}
+	}
+}
+
+func BenchmarkSwaggerValidatorGin(b *testing.B) {
+	r := createEngineGin(benchAPI(func(*gin.Context) {}))
+	benchmarkHandler(b, r)
+}
+
+func BenchmarkSwaggerValidatorEcho(b *testing.B) {
+	r := createEngineEcho(benchAPI(func(echo.Context) error { return nil }))
+	benchmarkHandler(b, r)
+}
+
+func benchmarkHandler(b *testing.B, h http.Handler) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		req := preparePostRequest("/validate-test/10?limit=5", benchPayload)
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+// largeAPI builds an api with n endpoints and n definitions
+func largeAPI(n int) *swagger.API {
+	endpoints := []*swagger.Endpoint{}
+	for i := 0; i < n; i++ {
+		endpoints = append(endpoints, endpoint.New("POST", fmt.Sprintf("/validate-test-%d", i), "Test the validator",
+			endpoint.Handler(func() {}),
+			endpoint.Body(payload{}, "Validation body", true),
+		))
+	}
+	api := swag.New(swag.Endpoints(endpoints...))
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("Definition%d", i)
+		api.Definitions[name] = swagger.Object{
+			Name:     name,
+			Type:     "object",
+			Required: []string{"foo"},
+			Properties: map[string]swagger.Property{
+				"foo": {Type: "string"},
+				"bar": {Type: "integer", Format: "int32"},
+			},
+		}
+	}
+	return api
+}
+
+func BenchmarkNewValidator(b *testing.B) {
+	for _, n := range []int{50, 100, 200} {
+		api := largeAPI(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sv.NewValidator(api)
+			}
+		})
+	}
+}
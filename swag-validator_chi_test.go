@@ -0,0 +1,126 @@
+package swagvalidator_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	swag "github.com/miketonks/swag"
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+	"github.com/stretchr/testify/assert"
+
+	sv "github.com/miketonks/swag-validator"
+)
+
+func createEngineChi(api *swagger.API, opts ...sv.Option) (r *chi.Mux) {
+	r = chi.NewRouter()
+	r.Use(sv.SwaggerValidatorChi(api, opts...))
+	api.Walk(func(path string, endpoint *swagger.Endpoint) {
+		h := endpoint.Handler.(http.HandlerFunc)
+
+		r.MethodFunc(endpoint.Method, path, h)
+	})
+	return
+}
+
+func TestConformanceChi(t *testing.T) {
+	testConformance(t, httpHandler, func(api *swagger.API) http.Handler {
+		return createEngineChi(api)
+	})
+}
+
+func TestInlineMiddlewareChi(t *testing.T) {
+	api := swag.New(
+		swag.BasePath("/api"),
+		swag.Endpoints(
+			endpoint.New("GET", "/pet/{petId}", "Find pet by ID",
+				endpoint.Handler(httpHandler),
+				endpoint.Path("petId", "integer", "", ""),
+			),
+			endpoint.New("GET", "/owner/{ownerId}", "Find owner by ID",
+				endpoint.Handler(httpHandler),
+				endpoint.Path("ownerId", "integer", "", ""),
+			),
+		))
+
+	// With runs the middleware after the route is matched, in a sub router
+	with := chi.NewRouter()
+	with.Route("/api", func(r chi.Router) {
+		r.With(sv.SwaggerValidatorChi(api)).Get("/pet/{petId}", httpHandler)
+		r.With(sv.SwaggerValidatorChi(api)).Get("/owner/{ownerId:[a-z0-9]+}", httpHandler)
+		r.Get("/other/{petId}", httpHandler)
+	})
+
+	// Use in a sub router runs the middleware with the partial route pattern /api/*
+	routes := func(r chi.Router) {
+		r.Use(sv.SwaggerValidatorChi(api))
+		r.Get("/pet/{petId}", httpHandler)
+		r.Get("/owner/{ownerId:[a-z0-9]+}", httpHandler)
+		r.Get("/other/{petId}", httpHandler)
+	}
+	subRouter := chi.NewRouter()
+	subRouter.Route("/api", routes)
+	api2 := chi.NewRouter()
+	routes(api2)
+	mounted := chi.NewRouter()
+	mounted.Mount("/api", api2)
+
+	testTable := []struct {
+		description      string
+		url              string
+		expectedStatus   int
+		expectedResponse map[string]string
+	}{
+		{
+			description:    "Valid path param",
+			url:            "/api/pet/10",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Invalid path param",
+			url:            "/api/pet/abc",
+			expectedStatus: 400,
+			expectedResponse: map[string]string{
+				"petId": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description:    "Valid path param, regexp route",
+			url:            "/api/owner/10",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Invalid path param, regexp route",
+			url:            "/api/owner/abc",
+			expectedStatus: 400,
+			expectedResponse: map[string]string{
+				"ownerId": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description:    "Route not in spec",
+			url:            "/api/other/abc",
+			expectedStatus: 200,
+		},
+	}
+
+	for name, r := range map[string]http.Handler{"With": with, "Use in a sub router": subRouter, "Use in a mounted router": mounted} {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range testTable {
+				t.Run(tt.description, func(t *testing.T) {
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+
+					assert.Equal(t, tt.expectedStatus, w.Code)
+					if tt.expectedResponse != nil {
+						var resp sv.ErrorResponse
+						unmarshalBody(w, &resp)
+						assert.Equal(t, tt.expectedResponse, resp.Details)
+					}
+				})
+			}
+		})
+	}
+}
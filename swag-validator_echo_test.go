@@ -3,9 +3,11 @@ package swagvalidator_test
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/labstack/echo/v4"
@@ -545,6 +547,222 @@ func TestOptionsReturnErrorsEcho(t *testing.T) {
 	})
 }
 
+func TestResponseValidationEcho(t *testing.T) {
+
+	api := swag.New(
+		swag.Endpoints(endpoint.New("GET", "/response-test/{id}", "Test the response validator",
+			endpoint.Handler(func(c echo.Context) error {
+				if c.Param("id") == "valid" {
+					return c.JSON(http.StatusOK, nested{Foo: "bar"})
+				}
+				c.Response().Header().Set("Content-Encoding", "identity")
+				return c.JSON(http.StatusOK, map[string]interface{}{"foo": 1})
+			}),
+			endpoint.Path("id", "string", "", ""),
+			endpoint.Response(http.StatusOK, nested{}, "ok"),
+		)))
+
+	invalidBody := `{"message":"Response validation error","details":{"foo":"Invalid type. Expected: string, given: integer"}}`
+
+	testTable := []struct {
+		description      string
+		opts             []sv.EchoOption
+		id               string
+		expectedStatus   int
+		expectedBody     string
+		expectedReported int
+	}{
+		{
+			description:    "Report mode, valid response",
+			opts:           []sv.EchoOption{sv.SetResponseValidation(sv.ResponseValidationReport)},
+			id:             "valid",
+			expectedStatus: 200,
+			expectedBody:   `{"foo":"bar"}`,
+		},
+		{
+			description:      "Report mode, invalid response is sent and reported",
+			opts:             []sv.EchoOption{sv.SetResponseValidation(sv.ResponseValidationReport)},
+			id:               "invalid",
+			expectedStatus:   200,
+			expectedBody:     `{"foo":1}`,
+			expectedReported: 1,
+		},
+		{
+			description:    "Strict mode, valid response",
+			opts:           []sv.EchoOption{sv.SetResponseValidation(sv.ResponseValidationStrict)},
+			id:             "valid",
+			expectedStatus: 200,
+			expectedBody:   `{"foo":"bar"}`,
+		},
+		{
+			description:      "Strict mode, invalid response is replaced",
+			opts:             []sv.EchoOption{sv.SetResponseValidation(sv.ResponseValidationStrict)},
+			id:               "invalid",
+			expectedStatus:   500,
+			expectedBody:     invalidBody,
+			expectedReported: 1,
+		},
+		{
+			description:      "Strict mode, invalid response is returned as an error",
+			opts:             []sv.EchoOption{sv.SetResponseValidation(sv.ResponseValidationStrict), sv.SetEchoReturnErrors(true)},
+			id:               "invalid",
+			expectedStatus:   500,
+			expectedBody:     invalidBody,
+			expectedReported: 1,
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			reported := []sv.ErrorResponse{}
+			opts := append(tc.opts, sv.SetResponseErrorHandler(func(r *http.Request, resp sv.ErrorResponse) {
+				reported = append(reported, resp)
+			}))
+			r := createEngineEcho(api, opts...)
+			r.HTTPErrorHandler = func(err error, c echo.Context) {
+				sve := err.(sv.ErrorResponse)
+				c.JSON(sve.StatusCode, sve)
+			}
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "/response-test/"+tc.id, nil)
+			if err != nil {
+				log.Fatalf("Error preparing request: %s", err)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+			assert.Len(t, reported, tc.expectedReported)
+			if tc.expectedStatus == 500 {
+				// the Content-* headers of the handler do not describe the error
+				assert.Equal(t, "application/json; charset=UTF-8", w.Header().Get("Content-Type"))
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+			}
+		})
+	}
+}
+
+func TestResponseValidationHijackEcho(t *testing.T) {
+
+	api := swag.New(
+		swag.Endpoints(endpoint.New("GET", "/hijack-test", "Test a handler taking over the connection",
+			endpoint.Handler(func(c echo.Context) error {
+				conn, rw, err := c.Response().Hijack()
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+				return rw.Flush()
+			}),
+			endpoint.Response(http.StatusOK, nested{}, "ok"),
+		)))
+
+	for _, mode := range []sv.ResponseValidation{sv.ResponseValidationReport, sv.ResponseValidationStrict} {
+		srv := httptest.NewServer(createEngineEcho(api, sv.SetResponseValidation(mode), sv.SetStatusValidation(mode)))
+
+		resp, err := http.Get(srv.URL + "/hijack-test")
+		if assert.NoError(t, err) {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "hijacked", string(body))
+		}
+		srv.Close()
+	}
+}
+
+func TestStatusValidationEcho(t *testing.T) {
+
+	api := swag.New(
+		swag.Endpoints(endpoint.New("GET", "/status-test/{status}", "Test the status validator",
+			endpoint.Handler(func(c echo.Context) error {
+				status, _ := strconv.Atoi(c.Param("status"))
+				if status >= 400 {
+					return echo.NewHTTPError(status)
+				}
+				return c.JSON(status, map[string]interface{}{})
+			}),
+			endpoint.Path("status", "integer", "", ""),
+			endpoint.Response(http.StatusOK, map[string]interface{}{}, "ok"),
+			endpoint.Response(http.StatusNotFound, map[string]interface{}{}, "not found"),
+		)))
+
+	testTable := []struct {
+		description    string
+		mode           sv.ResponseValidation
+		status         int
+		expectedStatus int
+		expectedCounts map[string]map[int]int
+	}{
+		{
+			description:    "Report mode, documented status",
+			mode:           sv.ResponseValidationReport,
+			status:         200,
+			expectedStatus: 200,
+			expectedCounts: map[string]map[int]int{},
+		},
+		{
+			description:    "Report mode, undocumented status is sent and counted",
+			mode:           sv.ResponseValidationReport,
+			status:         201,
+			expectedStatus: 201,
+			expectedCounts: map[string]map[int]int{"GET/status-test/:status": {201: 1}},
+		},
+		{
+			description:    "Report mode, documented status of an HTTPError",
+			mode:           sv.ResponseValidationReport,
+			status:         404,
+			expectedStatus: 404,
+			expectedCounts: map[string]map[int]int{},
+		},
+		{
+			description:    "Report mode, undocumented status of an HTTPError is sent and counted",
+			mode:           sv.ResponseValidationReport,
+			status:         409,
+			expectedStatus: 409,
+			expectedCounts: map[string]map[int]int{"GET/status-test/:status": {409: 1}},
+		},
+		{
+			description:    "Strict mode, undocumented status is replaced",
+			mode:           sv.ResponseValidationStrict,
+			status:         201,
+			expectedStatus: 500,
+			expectedCounts: map[string]map[int]int{"GET/status-test/:status": {201: 1}},
+		},
+		{
+			description:    "Strict mode, undocumented status of an HTTPError is replaced",
+			mode:           sv.ResponseValidationStrict,
+			status:         409,
+			expectedStatus: 500,
+			expectedCounts: map[string]map[int]int{"GET/status-test/:status": {409: 1}},
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			counts := sv.NewStatusCounts()
+			r := createEngineEcho(api, sv.SetStatusValidation(tc.mode), sv.SetStatusCounts(counts))
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", fmt.Sprintf("/status-test/%d", tc.status), nil)
+			if err != nil {
+				log.Fatalf("Error preparing request: %s", err)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedCounts, counts.Counts())
+			if tc.expectedStatus == 500 {
+				var resp sv.ErrorResponse
+				unmarshalBody(w, &resp)
+				assert.Equal(t, map[string]string{"status": fmt.Sprintf("Undocumented response status %d", tc.status)}, resp.Details)
+			}
+		})
+	}
+}
+
 func unmarshalBody(w *httptest.ResponseRecorder, v interface{}) {
 	if w.Body != nil && w.Body.String() != "" {
 		err := json.Unmarshal(w.Body.Bytes(), v)
@@ -553,3 +771,19 @@ func unmarshalBody(w *httptest.ResponseRecorder, v interface{}) {
 		}
 	}
 }
+
+func TestConcurrentEcho(t *testing.T) {
+	api := swag.New(swag.Endpoints(endpoint.New("GET", "/validate-test/{id}", "Test concurrent requests",
+		endpoint.Handler(handler),
+		endpoint.Path("id", "integer", "int64", ""),
+	)))
+
+	r := createEngineEcho(api)
+	testConcurrentRequests(t, r)
+}
+
+func TestConformanceEcho(t *testing.T) {
+	testConformance(t, handler, func(api *swagger.API) http.Handler {
+		return createEngineEcho(api)
+	})
+}
@@ -0,0 +1,160 @@
+package swagvalidator_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	swag "github.com/miketonks/swag"
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+	"github.com/stretchr/testify/assert"
+
+	sv "github.com/miketonks/swag-validator"
+)
+
+func fiberHandler(c *fiber.Ctx) error {
+	return c.SendStatus(http.StatusOK)
+}
+
+// fiberApp serves a fiber app through the net/http interface used by the shared tests
+type fiberApp struct {
+	*fiber.App
+}
+
+func (a fiberApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp, err := a.Test(r, -1)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// createEngineFiber adds the validator to every route, or with app.Use if use is set
+func createEngineFiber(api *swagger.API, use bool, opts ...sv.Option) fiberApp {
+	r := fiber.New()
+	validator := sv.SwaggerValidatorFiber(api, opts...)
+	if use {
+		r.Use(validator)
+	}
+	api.Walk(func(path string, endpoint *swagger.Endpoint) {
+		h := endpoint.Handler.(func(*fiber.Ctx) error)
+		path = swag.ColonPath(path)
+
+		if use {
+			r.Add(endpoint.Method, path, h)
+		} else {
+			r.Add(endpoint.Method, path, validator, h)
+		}
+	})
+	return fiberApp{r}
+}
+
+func TestConformanceFiber(t *testing.T) {
+	t.Run("Route middleware", func(t *testing.T) {
+		testConformance(t, fiberHandler, func(api *swagger.API) http.Handler {
+			return createEngineFiber(api, false)
+		})
+	})
+	t.Run("App middleware", func(t *testing.T) {
+		testConformance(t, fiberHandler, func(api *swagger.API) http.Handler {
+			return createEngineFiber(api, true)
+		})
+	})
+	t.Run("App middleware with a root endpoint", func(t *testing.T) {
+		// the app.Use prefix / must not be taken for the root endpoint
+		api := swag.New(
+			swag.Endpoints(
+				endpoint.New("GET", "/", "Search",
+					endpoint.Handler(fiberHandler),
+					endpoint.Query("q", "string", "", "", true),
+				),
+				endpoint.New("GET", "/pet/{petId}", "Find pet by ID",
+					endpoint.Handler(fiberHandler),
+					endpoint.Path("petId", "integer", "", ""),
+				),
+			))
+		r := createEngineFiber(api, true)
+
+		testTable := []conformanceCase{
+			{
+				description:    "Root endpoint",
+				method:         "GET",
+				url:            "/?q=cat",
+				expectedStatus: 200,
+			},
+			{
+				description:    "Root endpoint, missing query param",
+				method:         "GET",
+				url:            "/",
+				expectedStatus: 400,
+				expectedResponse: map[string]interface{}{
+					"q": "q is required",
+				},
+			},
+			{
+				description:    "Other endpoint",
+				method:         "GET",
+				url:            "/pet/10",
+				expectedStatus: 200,
+			},
+			{
+				description:    "Other endpoint, invalid path param",
+				method:         "GET",
+				url:            "/pet/abc",
+				expectedStatus: 400,
+				expectedResponse: map[string]interface{}{
+					"petId": "Invalid type. Expected: integer, given: string",
+				},
+			},
+		}
+
+		for _, tt := range testTable {
+			t.Run(tt.description, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, nil))
+
+				assert.Equal(t, tt.expectedStatus, w.Code)
+				if tt.expectedResponse != nil {
+					var body map[string]interface{}
+					unmarshalBody(w, &body)
+					assert.Equal(t, tt.expectedResponse, body["details"])
+				}
+			})
+		}
+	})
+}
+
+func TestOptionsReturnErrorsFiber(t *testing.T) {
+
+	api := swag.New(
+		swag.Endpoints(endpoint.New("GET", "/error-handler-test/{id}", "Test the validator",
+			endpoint.Handler(fiberHandler),
+			endpoint.Path("id", "integer", "integer", ""),
+		)))
+
+	r := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			sve := err.(sv.ErrorResponse)
+			sve.Message = "HANDLED: " + sve.Message
+			return c.Status(sve.StatusCode).JSON(sve)
+		},
+	})
+	r.Get("/error-handler-test/:id", sv.SwaggerValidatorFiber(api, sv.SetEchoReturnErrors(true)), fiberHandler)
+
+	w := httptest.NewRecorder()
+	fiberApp{r}.ServeHTTP(w, httptest.NewRequest("GET", "/error-handler-test/foo", nil))
+
+	var resp sv.ErrorResponse
+	unmarshalBody(w, &resp)
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, "HANDLED: Validation error", resp.Message)
+	assert.Equal(t, map[string]string{"id": "Invalid type. Expected: integer, given: string"}, resp.Details)
+}
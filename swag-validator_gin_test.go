@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -17,10 +18,16 @@ import (
 	sv "github.com/miketonks/swag-validator"
 )
 
-func createEngineGin(api *swagger.API) (r *gin.Engine) {
+func createEngineGin(api *swagger.API, opts ...sv.GinOption) (r *gin.Engine) {
+	return createEngineGinWith(api, nil, opts...)
+}
+
+// createEngineGinWith registers the middlewares in mw ahead of the validator
+func createEngineGinWith(api *swagger.API, mw []gin.HandlerFunc, opts ...sv.GinOption) (r *gin.Engine) {
 	gin.SetMode(gin.ReleaseMode)
 	r = gin.New()
-	r.Use(sv.SwaggerValidator(api))
+	r.Use(mw...)
+	r.Use(sv.SwaggerValidator(api, opts...))
 	api.Walk(func(path string, endpoint *swagger.Endpoint) {
 		h := endpoint.Handler.(func(c *gin.Context))
 		path = swag.ColonPath(path)
@@ -174,9 +181,6 @@ func TestPathGin(t *testing.T) {
 			}},
 	}
 
-	// Can't bind multiple endpoints to the same handler
-	// Even if the handler is a lambda, it still does not work.
-	// Therefore have to create a new api for each endpoint iteratively
 	for _, testCase := range testTable {
 		api := swag.New(swag.Endpoints(endpoint.New("GET", "/validate-test"+testCase.urlWParm, "Test the validator",
 			endpoint.Handler(func(*gin.Context) {}),
@@ -514,3 +518,356 @@ func TestPayloadGin(t *testing.T) {
 		})
 	}
 }
+
+func TestConcurrentGin(t *testing.T) {
+	api := swag.New(swag.Endpoints(endpoint.New("GET", "/validate-test/{id}", "Test concurrent requests",
+		endpoint.Handler(func(*gin.Context) {}),
+		endpoint.Path("id", "integer", "int64", ""),
+	)))
+
+	r := createEngineGin(api)
+	testConcurrentRequests(t, r)
+}
+
+func TestOptionsReturnErrorsGin(t *testing.T) {
+
+	api := swag.New(
+		swag.Endpoints(endpoint.New("GET", "/error-handler-test/{id}", "Test the validator",
+			endpoint.Handler(func(*gin.Context) {}),
+			endpoint.Path("id", "integer", "integer", ""),
+		)))
+
+	errorHandler := func(c *gin.Context) {
+		c.Next()
+		if err := c.Errors.Last(); err != nil {
+			sve := err.Err.(sv.ErrorResponse)
+			sve.Message = "HANDLED: " + sve.Message
+			c.JSON(sve.StatusCode, sve)
+		}
+	}
+
+	r := createEngineGinWith(api, []gin.HandlerFunc{errorHandler}, sv.SetGinReturnErrors(true))
+
+	t.Run("ReturnErrors", func(t *testing.T) {
+
+		w := httptest.NewRecorder()
+
+		req, err := http.NewRequest("GET", "/error-handler-test/foo", nil)
+		if err != nil {
+			log.Fatalf("Error preparing request: %s", err)
+		}
+
+		r.ServeHTTP(w, req)
+
+		var resp sv.ErrorResponse
+		unmarshalBody(w, &resp)
+		assert.Equal(t, 400, w.Code)
+		assert.Equal(t, "HANDLED: Validation error", resp.Message)
+		assert.Equal(t, map[string]string{"id": "Invalid type. Expected: integer, given: string"}, resp.Details)
+	})
+}
+
+func TestResponseValidationGin(t *testing.T) {
+
+	api := swag.New(
+		swag.Endpoints(endpoint.New("GET", "/response-test/{id}", "Test the response validator",
+			endpoint.Handler(func(c *gin.Context) {
+				switch c.Param("id") {
+				case "valid":
+					c.JSON(http.StatusOK, nested{Foo: "bar"})
+				case "xml":
+					c.Data(http.StatusOK, "application/xml", []byte("<nested/>"))
+				default:
+					c.Header("Content-Encoding", "identity")
+					c.JSON(http.StatusOK, map[string]interface{}{"foo": 1})
+				}
+			}),
+			endpoint.Path("id", "string", "", ""),
+			endpoint.Response(http.StatusOK, nested{}, "ok"),
+		)))
+
+	testTable := []struct {
+		description      string
+		mode             sv.ResponseValidation
+		id               string
+		expectedStatus   int
+		expectedBody     string
+		expectedReported int
+	}{
+		{
+			description:    "Strict mode, response that is not JSON is not validated",
+			mode:           sv.ResponseValidationStrict,
+			id:             "xml",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Report mode, valid response",
+			mode:           sv.ResponseValidationReport,
+			id:             "valid",
+			expectedStatus: 200,
+			expectedBody:   `{"foo":"bar"}`,
+		},
+		{
+			description:      "Report mode, invalid response is sent and reported",
+			mode:             sv.ResponseValidationReport,
+			id:               "invalid",
+			expectedStatus:   200,
+			expectedBody:     `{"foo":1}`,
+			expectedReported: 1,
+		},
+		{
+			description:    "Strict mode, valid response",
+			mode:           sv.ResponseValidationStrict,
+			id:             "valid",
+			expectedStatus: 200,
+			expectedBody:   `{"foo":"bar"}`,
+		},
+		{
+			description:      "Strict mode, invalid response is replaced",
+			mode:             sv.ResponseValidationStrict,
+			id:               "invalid",
+			expectedStatus:   500,
+			expectedBody:     `{"message":"Response validation error","details":{"foo":"Invalid type. Expected: string, given: integer"}}`,
+			expectedReported: 1,
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			reported := []sv.ErrorResponse{}
+			r := createEngineGin(api,
+				sv.SetResponseValidation(tc.mode),
+				sv.SetResponseErrorHandler(func(r *http.Request, resp sv.ErrorResponse) {
+					reported = append(reported, resp)
+				}),
+			)
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "/response-test/"+tc.id, nil)
+			if err != nil {
+				log.Fatalf("Error preparing request: %s", err)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, w.Body.String())
+			}
+			assert.Len(t, reported, tc.expectedReported)
+			if tc.expectedStatus == 500 {
+				// the Content-* headers of the handler do not describe the error
+				assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+			}
+		})
+	}
+}
+
+func TestStatusValidationGin(t *testing.T) {
+
+	api := swag.New(
+		swag.Endpoints(endpoint.New("GET", "/status-test/{status}", "Test the status validator",
+			endpoint.Handler(func(c *gin.Context) {
+				status, _ := strconv.Atoi(c.Param("status"))
+				c.JSON(status, gin.H{})
+			}),
+			endpoint.Path("status", "integer", "", ""),
+			endpoint.Response(http.StatusOK, gin.H{}, "ok"),
+		)))
+
+	testTable := []struct {
+		description    string
+		mode           sv.ResponseValidation
+		status         int
+		expectedStatus int
+		expectedCounts map[string]map[int]int
+	}{
+		{
+			description:    "Report mode, documented status",
+			mode:           sv.ResponseValidationReport,
+			status:         200,
+			expectedStatus: 200,
+			expectedCounts: map[string]map[int]int{},
+		},
+		{
+			description:    "Report mode, undocumented status is sent and counted",
+			mode:           sv.ResponseValidationReport,
+			status:         404,
+			expectedStatus: 404,
+			expectedCounts: map[string]map[int]int{"GET/status-test/:status": {404: 1}},
+		},
+		{
+			description:    "Strict mode, undocumented status is replaced",
+			mode:           sv.ResponseValidationStrict,
+			status:         409,
+			expectedStatus: 500,
+			expectedCounts: map[string]map[int]int{"GET/status-test/:status": {409: 1}},
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			counts := sv.NewStatusCounts()
+			r := createEngineGin(api, sv.SetStatusValidation(tc.mode), sv.SetStatusCounts(counts))
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", fmt.Sprintf("/status-test/%d", tc.status), nil)
+			if err != nil {
+				log.Fatalf("Error preparing request: %s", err)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedCounts, counts.Counts())
+			if tc.expectedStatus == 500 {
+				var resp sv.ErrorResponse
+				unmarshalBody(w, &resp)
+				assert.Equal(t, map[string]string{"status": fmt.Sprintf("Undocumented response status %d", tc.status)}, resp.Details)
+			}
+		})
+	}
+}
+
+type petHandlers struct{}
+
+func (petHandlers) get(*gin.Context) {}
+
+func TestRoutingGin(t *testing.T) {
+	shared := func(*gin.Context) {}
+	closure := func() func(*gin.Context) {
+		return func(*gin.Context) {}
+	}
+	var h petHandlers
+
+	api := swag.New(
+		swag.BasePath("/api"),
+		swag.Endpoints(
+			endpoint.New("GET", "/shared-int/{id}", "Shared handler",
+				endpoint.Handler(shared),
+				endpoint.Path("id", "integer", "", ""),
+			),
+			endpoint.New("GET", "/shared-uuid/{id}", "Shared handler",
+				endpoint.Handler(shared),
+				endpoint.Path("id", "string", "uuid", ""),
+			),
+			endpoint.New("GET", "/closure-int/{id}", "Closure handler",
+				endpoint.Handler(closure()),
+				endpoint.Path("id", "integer", "", ""),
+			),
+			endpoint.New("GET", "/closure-uuid/{id}", "Closure handler",
+				endpoint.Handler(closure()),
+				endpoint.Path("id", "string", "uuid", ""),
+			),
+			endpoint.New("GET", "/method-value/{id}", "Method value handler",
+				endpoint.Handler(h.get),
+				endpoint.Path("id", "integer", "", ""),
+			),
+			endpoint.New("POST", "/method-value/{id}", "Method value handler",
+				endpoint.Handler(h.get),
+				endpoint.Path("id", "string", "uuid", ""),
+			),
+		))
+
+	r := createEngineGin(api)
+
+	testTable := []struct {
+		description      string
+		method           string
+		url              string
+		expectedStatus   int
+		expectedResponse map[string]interface{}
+	}{
+		{
+			description:    "Shared handler, integer route",
+			method:         "GET",
+			url:            "/api/shared-int/" + testUUID,
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"id": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description:    "Shared handler, uuid route",
+			method:         "GET",
+			url:            "/api/shared-uuid/10",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"id": "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "Closure handler, integer route",
+			method:         "GET",
+			url:            "/api/closure-int/" + testUUID,
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"id": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description:    "Closure handler, uuid route",
+			method:         "GET",
+			url:            "/api/closure-uuid/10",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"id": "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "Method value handler, GET route",
+			method:         "GET",
+			url:            "/api/method-value/" + testUUID,
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"id": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description:    "Method value handler, POST route",
+			method:         "POST",
+			url:            "/api/method-value/10",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"id": "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "Method value handler, valid POST route",
+			method:         "POST",
+			url:            "/api/method-value/" + testUUID,
+			expectedStatus: 200,
+		},
+		{
+			description:    "Route not in spec",
+			method:         "GET",
+			url:            "/api/other",
+			expectedStatus: 404,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.description, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				log.Fatalf("Error preparing request: %s", err)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedResponse != nil {
+				var body map[string]interface{}
+				unmarshalBody(w, &body)
+				assert.Equal(t, tt.expectedResponse, body["details"])
+			}
+		})
+	}
+}
+
+func TestConformanceGin(t *testing.T) {
+	testConformance(t, func(*gin.Context) {}, func(api *swagger.API) http.Handler {
+		return createEngineGin(api)
+	})
+}
@@ -0,0 +1,93 @@
+package swagvalidator_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	swag "github.com/miketonks/swag"
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+	"github.com/stretchr/testify/assert"
+
+	sv "github.com/miketonks/swag-validator"
+)
+
+func createEngineGorilla(api *swagger.API, opts ...sv.Option) (r *mux.Router) {
+	r = mux.NewRouter()
+	r.Use(sv.SwaggerValidatorGorilla(api, opts...))
+	api.Walk(func(path string, endpoint *swagger.Endpoint) {
+		h := endpoint.Handler.(http.HandlerFunc)
+
+		r.HandleFunc(path, h).Methods(endpoint.Method)
+	})
+	return
+}
+
+func TestConformanceGorilla(t *testing.T) {
+	testConformance(t, httpHandler, func(api *swagger.API) http.Handler {
+		return createEngineGorilla(api)
+	})
+}
+
+func TestRoutesGorilla(t *testing.T) {
+	api := swag.New(
+		swag.BasePath("/api"),
+		swag.Endpoints(endpoint.New("GET", "/pet/{petId}/tags/{tag}", "Find pet tag",
+			endpoint.Handler(httpHandler),
+			endpoint.Path("petId", "integer", "", ""),
+			endpoint.Path("tag", "string", "uuid", ""),
+		)))
+
+	r := mux.NewRouter()
+	r.Use(sv.SwaggerValidatorGorilla(api))
+	r.HandleFunc("/api/pet/{petId:[a-z0-9]+}/tags/{tag:[a-f0-9-]{1,36}}", httpHandler).Methods("GET")
+	r.HandleFunc("/api/other/{petId}", httpHandler).Methods("GET")
+
+	testTable := []struct {
+		description      string
+		url              string
+		expectedStatus   int
+		expectedResponse map[string]string
+	}{
+		{
+			description:    "Valid path params, template with patterns",
+			url:            "/api/pet/10/tags/" + testUUID,
+			expectedStatus: 200,
+		},
+		{
+			description:    "Invalid path params, template with patterns",
+			url:            "/api/pet/abc/tags/abc",
+			expectedStatus: 400,
+			expectedResponse: map[string]string{
+				"petId": "Invalid type. Expected: integer, given: string",
+				"tag":   "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "Route not in spec",
+			url:            "/api/other/abc",
+			expectedStatus: 200,
+		},
+		{
+			description:    "No matching route",
+			url:            "/api/unknown",
+			expectedStatus: 404,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.description, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedResponse != nil {
+				var resp sv.ErrorResponse
+				unmarshalBody(w, &resp)
+				assert.Equal(t, tt.expectedResponse, resp.Details)
+			}
+		})
+	}
+}
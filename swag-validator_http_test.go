@@ -0,0 +1,295 @@
+package swagvalidator_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	swag "github.com/miketonks/swag"
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+	"github.com/stretchr/testify/assert"
+
+	sv "github.com/miketonks/swag-validator"
+)
+
+func httpHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func createEngineHTTP(api *swagger.API, opts ...sv.Option) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", httpHandler)
+	return sv.SwaggerValidatorHTTP(api, opts...)(mux)
+}
+
+func TestConformanceHTTP(t *testing.T) {
+	testConformance(t, httpHandler, func(api *swagger.API) http.Handler {
+		return createEngineHTTP(api)
+	})
+}
+
+func TestPathMatchingHTTP(t *testing.T) {
+	api := swag.New(
+		swag.BasePath("/api/"),
+		swag.Endpoints(
+			endpoint.New("GET", "/pet/{petId}", "Find pet by ID",
+				endpoint.Handler(httpHandler),
+				endpoint.Path("petId", "integer", "", ""),
+			),
+			endpoint.New("DELETE", "/pet/{id}", "Delete pet by ID",
+				endpoint.Handler(httpHandler),
+				endpoint.Path("id", "string", "uuid", ""),
+			),
+			endpoint.New("GET", "/pet/findByStatus", "Find pets by status",
+				endpoint.Handler(httpHandler),
+				endpoint.Query("status", "string", "", "", true),
+			),
+			endpoint.New("GET", "/pet/{petId}/tags/{tag}", "Find pet tag",
+				endpoint.Handler(httpHandler),
+				endpoint.Path("petId", "integer", "", ""),
+				endpoint.PathMap(map[string]swagger.Parameter{
+					"tag": {Type: "string", Pattern: "^[a-z ]+$"},
+				}),
+			),
+			endpoint.New("GET", "/owner/{ownerId}/", "Find owner by ID",
+				endpoint.Handler(httpHandler),
+				endpoint.Path("ownerId", "integer", "", ""),
+			),
+			endpoint.New("GET", "/files/{name}.json", "Get JSON file",
+				endpoint.Handler(httpHandler),
+				endpoint.PathMap(map[string]swagger.Parameter{
+					"name": {Type: "string", Pattern: "^[a-z]+$"},
+				}),
+			),
+		))
+
+	r := createEngineHTTP(api)
+
+	testTable := []struct {
+		description      string
+		method           string
+		url              string
+		expectedStatus   int
+		expectedResponse map[string]interface{}
+	}{
+		{
+			description:    "Path param",
+			method:         "GET",
+			url:            "/api/pet/10",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Invalid path param",
+			method:         "GET",
+			url:            "/api/pet/abc",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"petId": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description:    "Same template, different method and param name",
+			method:         "DELETE",
+			url:            "/api/pet/10",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"id": "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "Static segment takes precedence over param",
+			method:         "GET",
+			url:            "/api/pet/findByStatus",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"status": "status is required",
+			},
+		},
+		{
+			description:    "Static segment not defined for method falls back to param",
+			method:         "DELETE",
+			url:            "/api/pet/findByStatus",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"id": "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "Multiple path params, escaped value",
+			method:         "GET",
+			url:            "/api/pet/10/tags/very%20grumpy",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Multiple path params, invalid value",
+			method:         "GET",
+			url:            "/api/pet/10/tags/GRUMPY",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"tag": "Does not match pattern '^[a-z ]+$'",
+			},
+		},
+		{
+			description:    "Path without basePath is not validated",
+			method:         "GET",
+			url:            "/pet/abc",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Method not in spec is not validated",
+			method:         "POST",
+			url:            "/api/pet/abc",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Trailing slash",
+			method:         "GET",
+			url:            "/api/owner/abc/",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"ownerId": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description:    "Trailing slash missing is not matched",
+			method:         "GET",
+			url:            "/api/owner/abc",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Path param with text in the segment",
+			method:         "GET",
+			url:            "/api/files/pets.json",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Path param with text in the segment, invalid value",
+			method:         "GET",
+			url:            "/api/files/PETS.json",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"name": "Does not match pattern '^[a-z]+$'",
+			},
+		},
+		{
+			description:    "Path param with different text in the segment is not matched",
+			method:         "GET",
+			url:            "/api/files/PETS.xml",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Empty path param is not matched",
+			method:         "GET",
+			url:            "/api/pet/",
+			expectedStatus: 200,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.description, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedResponse != nil {
+				var body map[string]interface{}
+				unmarshalBody(w, &body)
+				assert.Equal(t, tt.expectedResponse, body["details"])
+			}
+		})
+	}
+}
+
+func TestResponseValidationHTTP(t *testing.T) {
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/response-test/") {
+		case "valid":
+			w.Write([]byte(`{"foo":"bar"}`))
+		case "missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte(`{"foo":1}`))
+		}
+	}
+
+	api := swag.New(
+		swag.Endpoints(endpoint.New("GET", "/response-test/{id}", "Test the response validator",
+			endpoint.Handler(h),
+			endpoint.Path("id", "string", "", ""),
+			endpoint.Response(http.StatusOK, nested{}, "ok"),
+		)))
+
+	invalidBody := `{"message":"Response validation error","details":{"foo":"Invalid type. Expected: string, given: integer"}}`
+	undocumentedBody := `{"message":"Response validation error","details":{"status":"Undocumented response status 404"}}`
+
+	testTable := []struct {
+		description      string
+		opts             []sv.Option
+		id               string
+		expectedStatus   int
+		expectedBody     string
+		expectedReported int
+	}{
+		{
+			description:    "Report mode, valid response",
+			opts:           []sv.Option{sv.SetResponseValidation(sv.ResponseValidationReport)},
+			id:             "valid",
+			expectedStatus: 200,
+			expectedBody:   `{"foo":"bar"}`,
+		},
+		{
+			description:      "Report mode, invalid response is sent and reported",
+			opts:             []sv.Option{sv.SetResponseValidation(sv.ResponseValidationReport)},
+			id:               "invalid",
+			expectedStatus:   200,
+			expectedBody:     `{"foo":1}`,
+			expectedReported: 1,
+		},
+		{
+			description:    "Strict mode, valid response",
+			opts:           []sv.Option{sv.SetResponseValidation(sv.ResponseValidationStrict)},
+			id:             "valid",
+			expectedStatus: 200,
+			expectedBody:   `{"foo":"bar"}`,
+		},
+		{
+			description:      "Strict mode, invalid response is replaced",
+			opts:             []sv.Option{sv.SetResponseValidation(sv.ResponseValidationStrict)},
+			id:               "invalid",
+			expectedStatus:   500,
+			expectedBody:     invalidBody,
+			expectedReported: 1,
+		},
+		{
+			description:      "Strict status validation, undocumented status is replaced",
+			opts:             []sv.Option{sv.SetStatusValidation(sv.ResponseValidationStrict)},
+			id:               "missing",
+			expectedStatus:   500,
+			expectedBody:     undocumentedBody,
+			expectedReported: 1,
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			reported := []sv.ErrorResponse{}
+			opts := append(tc.opts, sv.SetResponseErrorHandler(func(r *http.Request, resp sv.ErrorResponse) {
+				reported = append(reported, resp)
+			}))
+			r := sv.SwaggerValidatorHTTP(api, opts...)(http.HandlerFunc(h))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/response-test/"+tc.id, nil))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+			assert.Len(t, reported, tc.expectedReported)
+		})
+	}
+}
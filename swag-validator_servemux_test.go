@@ -0,0 +1,151 @@
+//go:build go1.22
+
+// The module declares go 1.20, enable the Go 1.22 ServeMux patterns for the tests
+//go:debug httpmuxgo121=0
+
+package swagvalidator_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	swag "github.com/miketonks/swag"
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+	"github.com/stretchr/testify/assert"
+
+	sv "github.com/miketonks/swag-validator"
+)
+
+func createEngineServeMux(api *swagger.API, opts ...sv.Option) *http.ServeMux {
+	mux := http.NewServeMux()
+	sv.RegisterServeMux(mux, api, opts...)
+	return mux
+}
+
+func TestConformanceServeMux(t *testing.T) {
+	testConformance(t, httpHandler, func(api *swagger.API) http.Handler {
+		return createEngineServeMux(api)
+	})
+}
+
+func TestRegisterServeMux(t *testing.T) {
+	var petID string
+	getPet := func(w http.ResponseWriter, r *http.Request) {
+		petID = r.PathValue("petId")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	api := swag.New(
+		swag.BasePath("/api"),
+		swag.Endpoints(
+			endpoint.New("GET", "/pet/{petId}", "Find pet by ID",
+				endpoint.Handler(getPet),
+				endpoint.Path("petId", "integer", "", ""),
+			),
+			endpoint.New("DELETE", "/pet/{petId}", "Delete pet by ID",
+				endpoint.Handler(http.HandlerFunc(httpHandler)),
+				endpoint.Path("petId", "string", "uuid", ""),
+			),
+		))
+
+	mux := createEngineServeMux(api)
+
+	t.Run("Valid path param reaches the handler", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/pet/10", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "10", petID)
+	})
+
+	t.Run("Invalid path param", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/pet/10", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var resp sv.ErrorResponse
+		unmarshalBody(w, &resp)
+		assert.Equal(t, map[string]string{"petId": "Field does not match format 'uuid'"}, resp.Details)
+	})
+
+	t.Run("Method not in spec", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/pet/10", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+
+	t.Run("Parameter names that are not Go identifiers", func(t *testing.T) {
+		var ownerID, petID string
+		api := swag.New(swag.Endpoints(endpoint.New("GET", "/owner/{owner-id}/pet/{1pet.id}", "Find pet of owner",
+			endpoint.Handler(func(w http.ResponseWriter, r *http.Request) {
+				ownerID, petID = r.PathValue("owner_id"), r.PathValue("_1pet_id")
+			}),
+			endpoint.Path("owner-id", "integer", "", ""),
+			endpoint.Path("1pet.id", "integer", "", ""),
+		)))
+		mux := createEngineServeMux(api)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/owner/1/pet/2", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1", ownerID)
+		assert.Equal(t, "2", petID)
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/owner/abc/pet/2", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var resp sv.ErrorResponse
+		unmarshalBody(w, &resp)
+		assert.Equal(t, map[string]string{"owner-id": "Invalid type. Expected: integer, given: string"}, resp.Details)
+	})
+
+	t.Run("Root and trailing slash paths match exactly", func(t *testing.T) {
+		api := swag.New(swag.Endpoints(
+			endpoint.New("GET", "/", "Search",
+				endpoint.Handler(httpHandler),
+				endpoint.Query("q", "string", "", "", true),
+			),
+			endpoint.New("GET", "/owner/{ownerId}/", "Find owner by ID",
+				endpoint.Handler(httpHandler),
+				endpoint.Path("ownerId", "integer", "", ""),
+			),
+		))
+		mux := createEngineServeMux(api)
+
+		testTable := []struct {
+			url            string
+			expectedStatus int
+		}{
+			{"/?q=cat", http.StatusOK},
+			{"/", http.StatusBadRequest},
+			{"/other/thing", http.StatusNotFound},
+			{"/owner/1/", http.StatusOK},
+			{"/owner/abc/", http.StatusBadRequest},
+			{"/owner/1/pets", http.StatusNotFound},
+		}
+		for _, tt := range testTable {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+			assert.Equal(t, tt.expectedStatus, w.Code, tt.url)
+		}
+	})
+
+	t.Run("Segments mixing parameters and text", func(t *testing.T) {
+		api := swag.New(swag.Endpoints(endpoint.New("GET", "/files/{name}.json", "Get JSON file",
+			endpoint.Handler(httpHandler),
+			endpoint.Path("name", "string", "", ""),
+		)))
+		assert.PanicsWithValue(t,
+			"swagvalidator: segment {name}.json of path /files/{name}.json mixes parameters and text, which ServeMux does not support",
+			func() { createEngineServeMux(api) })
+	})
+
+	t.Run("Handler is not an http.Handler", func(t *testing.T) {
+		api := swag.New(swag.Endpoints(endpoint.New("GET", "/pet", "List pets",
+			endpoint.Handler(func() {}),
+		)))
+		assert.Panics(t, func() { createEngineServeMux(api) })
+	})
+}
@@ -3,8 +3,18 @@ package swagvalidator_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	swag "github.com/miketonks/swag"
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+	"github.com/stretchr/testify/assert"
+	"github.com/xeipuuv/gojsonschema"
 )
 
 var testUUID = "00000000-0000-0000-0000-000000000000"
@@ -53,6 +63,256 @@ func preparePostRequest(url string, body interface{}) *http.Request {
 	if err != nil {
 		log.Fatalf("Error preparing request: %s", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
 	return req
 }
+
+// testConcurrentRequests sends valid and invalid requests to /validate-test/{id} in parallel,
+// run with -race to check the middleware does not share mutable state between requests
+func testConcurrentRequests(t *testing.T, h http.Handler) {
+	t.Run("parallel", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			i := i
+			t.Run(fmt.Sprintf("request %d", i), func(t *testing.T) {
+				t.Parallel()
+
+				id, expectedStatus := fmt.Sprint(i), http.StatusOK
+				if i%2 == 1 {
+					id, expectedStatus = "abc", http.StatusBadRequest
+				}
+
+				w := httptest.NewRecorder()
+				req, err := http.NewRequest("GET", "/validate-test/"+id, nil)
+				if err != nil {
+					log.Fatalf("Error preparing request: %s", err)
+				}
+				h.ServeHTTP(w, req)
+
+				assert.Equal(t, expectedStatus, w.Code)
+				if expectedStatus == http.StatusBadRequest {
+					assert.Contains(t, w.Body.String(), "Invalid type. Expected: integer, given: string")
+				}
+			})
+		}
+	})
+
+	// the middleware must not change the locale of other gojsonschema users
+	assert.Equal(t, gojsonschema.DefaultLocale{}, gojsonschema.Locale)
+}
+
+type conformanceCase struct {
+	description      string
+	method           string
+	url              string
+	body             interface{}
+	expectedStatus   int
+	expectedResponse map[string]interface{}
+}
+
+// testConformance runs the query, path and payload validation cases shared by all framework adapters.
+// createEngine registers the endpoints of api with the framework under test and h as their handler.
+func testConformance(t *testing.T, h interface{}, createEngine func(api *swagger.API) http.Handler) {
+	api := swag.New(
+		swag.BasePath("/api"),
+		swag.Endpoints(
+			endpoint.New("GET", "/validate-test", "Test query params",
+				endpoint.Handler(h),
+				endpoint.QueryMap(map[string]swagger.Parameter{
+					"int_param": {
+						Type: "integer",
+					},
+					"uuid_param": {
+						Type:   "string",
+						Format: "uuid",
+					},
+					"enum_param": {
+						Type: "string",
+						Enum: []string{"foo", "bar"},
+					},
+				}),
+			),
+			endpoint.New("GET", "/validate-test/int-test/{int_id}", "Test path params",
+				endpoint.Handler(h),
+				endpoint.Path("int_id", "integer", "integer", ""),
+			),
+			endpoint.New("GET", "/validate-test/uuid-test/{uuid_id}", "Test path params",
+				endpoint.Handler(h),
+				endpoint.Path("uuid_id", "string", "uuid", ""),
+			),
+			endpoint.New("POST", "/validate-test", "Test the validator",
+				endpoint.Handler(h),
+				endpoint.Body(payload{}, "Validation body", true),
+			),
+		))
+
+	r := createEngine(api)
+
+	testTable := []conformanceCase{
+		{
+			description:    "Non-int value in an int query param",
+			method:         "GET",
+			url:            "/api/validate-test?int_param=abc",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"int_param": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description:    "Int value in an int query param",
+			method:         "GET",
+			url:            "/api/validate-test?int_param=10",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Non-UUID value in an uuid query param",
+			method:         "GET",
+			url:            "/api/validate-test?uuid_param=abc",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"uuid_param": "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "Not allowed enum value in enum query param",
+			method:         "GET",
+			url:            "/api/validate-test?enum_param=baz",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"enum_param": "Must be one of the following: \"foo\", \"bar\"",
+			},
+		},
+		{
+			description:    "non-int path param",
+			method:         "GET",
+			url:            "/api/validate-test/int-test/abc",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"int_id": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description:    "int path param",
+			method:         "GET",
+			url:            "/api/validate-test/int-test/10",
+			expectedStatus: 200,
+		},
+		{
+			description:    "non-uuid path param",
+			method:         "GET",
+			url:            "/api/validate-test/uuid-test/10",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"uuid_id": "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "uuid path param",
+			method:         "GET",
+			url:            "/api/validate-test/uuid-test/" + testUUID,
+			expectedStatus: 200,
+		},
+		{
+			description:    "Scalar uuid tag with non-uuid value",
+			method:         "POST",
+			url:            "/api/validate-test",
+			body:           payload{FormatString: "not-a-uuid"},
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"format_str": "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "Non-UUID string in a UUID array",
+			method:         "POST",
+			url:            "/api/validate-test",
+			body:           payload{FormatStringArr: []string{"not-a-uuid"}},
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"format_str_arr.0": "Field does not match format 'uuid'",
+			},
+		},
+		{
+			description:    "String shorter than minimum required",
+			method:         "POST",
+			url:            "/api/validate-test",
+			body:           payload{MinLenString: "1234"},
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"min_len_str": "String length must be greater than or equal to 5",
+			},
+		},
+		{
+			description:    "Number is greater than allowed",
+			method:         "POST",
+			url:            "/api/validate-test",
+			body:           payload{Maximum: 2},
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"maximum": "Must be less than or equal to 1",
+			},
+		},
+		{
+			description:    "Nested struct field is missing",
+			method:         "POST",
+			url:            "/api/validate-test",
+			body:           payload{Nested: &nested{}},
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"nested.foo": "foo is required",
+			},
+		},
+		{
+			description:    "Unique array contains non-unique items",
+			method:         "POST",
+			url:            "/api/validate-test",
+			body:           payload{UniqueItemsAarr: []string{"foo", "foo"}},
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"unique_items_arr": "array items[0,1] must be unique",
+			},
+		},
+		{
+			description:    "Valid payload",
+			method:         "POST",
+			url:            "/api/validate-test",
+			body:           payload{FormatString: testUUID, Nested: &nested{Foo: "bar"}},
+			expectedStatus: 200,
+		},
+		{
+			description:    "Invalid JSON",
+			method:         "POST",
+			url:            "/api/validate-test",
+			body:           "{",
+			expectedStatus: 400,
+			expectedResponse: map[string]interface{}{
+				"body": "Invalid JSON format",
+			},
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.description, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			var req *http.Request
+			if s, ok := tt.body.(string); ok {
+				req = httptest.NewRequest(tt.method, tt.url, strings.NewReader(s))
+				req.Header.Set("Content-Type", "application/json")
+			} else if tt.body != nil {
+				req = preparePostRequest(tt.url, tt.body)
+			} else {
+				req = httptest.NewRequest(tt.method, tt.url, nil)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedResponse != nil {
+				var body map[string]interface{}
+				unmarshalBody(w, &body)
+				assert.Equal(t, tt.expectedResponse, body["details"])
+			}
+		})
+	}
+}
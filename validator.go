@@ -0,0 +1,477 @@
+package swagvalidator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/miketonks/swag"
+	"github.com/miketonks/swag/swagger"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const (
+	// definitionsID is the $id of the schema holding the definitions shared by all endpoints
+	definitionsID = "http://swag-validator/definitions.json"
+	endpointID    = "http://swag-validator/endpoints/%d.json"
+	responseID    = "http://swag-validator/endpoints/%d/responses/%s.json"
+)
+
+// Validator validates http requests against the endpoints of a swagger API.
+// It does not depend on any web framework, the gin and echo middlewares are thin adapters over it.
+type Validator struct {
+	endpoints map[string]*validatorEndpoint
+	locale    locale
+	options   *Options
+}
+
+type validatorEndpoint struct {
+	endpoint *swagger.Endpoint
+	// schema is compiled once when the validator is built, err is set if compilation failed
+	schema *gojsonschema.Schema
+	err    error
+	// properties of the schema, used to coerce path, query, header and form values
+	properties map[string]interface{}
+	// headers are the names of the header parameters
+	headers []string
+	// locations of the parameters, by name, e.g. query or formData
+	locations map[string]string
+	// maxBodySize of the request body in bytes, 0 for no limit
+	maxBodySize int64
+	// fileLimits of the file parameters, by name
+	fileLimits map[string]FileLimits
+	// responses holds the compiled schemas of the documented responses, by status code
+	responses map[string]*responseSchema
+}
+
+// Result of validating a request
+type Result struct {
+	// Endpoint matched by the route key, nil if the route is not in the swagger spec
+	Endpoint *swagger.Endpoint
+	// Error is set when the request failed validation
+	Error *ErrorResponse
+}
+
+// Valid returns true if the request passed validation, or was not validated at all
+func (r *Result) Valid() bool {
+	return r.Error == nil
+}
+
+// RouteKey returns the key ValidateRequest uses to find an endpoint, e.g. "GET/api/pet/:petId"
+func RouteKey(method, path string) string {
+	return strings.ToUpper(method) + path
+}
+
+// NewValidator builds a Validator for every endpoint with a handler in api.
+// Endpoints are keyed by RouteKey(method, basePath + colon path).
+// opts are the middleware options, the ones checking requests apply to ValidateRequest.
+func NewValidator(api *swagger.API, opts ...Option) *Validator {
+	v := &Validator{
+		endpoints: map[string]*validatorEndpoint{},
+		locale:    CustomLocale{},
+		options:   buildOptions(opts),
+	}
+	registerFormatCheckers()
+
+	// Definitions are added to the loader once and referenced from every endpoint schema
+	loader := gojsonschema.NewSchemaLoader()
+	defsErr := loader.AddSchemas(gojsonschema.NewGoLoader(map[string]interface{}{
+		"$id":         definitionsID,
+		"definitions": buildSchemaDefinitions(api),
+	}))
+
+	n := 0
+	for _, p := range api.Paths {
+		for _, e := range []*swagger.Endpoint{
+			p.Delete,
+			p.Get,
+			p.Post,
+			p.Put,
+			p.Patch,
+			p.Head,
+			p.Options,
+			p.Trace,
+			p.Connect} {
+			if e != nil && e.Handler != nil {
+				ve := compileEndpoint(loader, n, e)
+				n++
+				if defsErr != nil {
+					ve.err = defsErr
+				}
+				key := endpointKey(api, e)
+				ve.maxBodySize = v.options.MaxBodySize
+				if size, found := v.options.EndpointMaxBodySizes[key]; found {
+					ve.maxBodySize = size
+				}
+				ve.fileLimits = endpointFileLimits(e, v.options.FileLimits[key])
+				if err := setCollectionFormats(ve.properties, v.options.CollectionFormats[key]); err != nil && ve.err == nil {
+					ve.err = err
+				}
+				v.endpoints[key] = ve
+			}
+		}
+	}
+	return v
+}
+
+// endpointKey returns the RouteKey of endpoint e, with the basePath of api applied
+func endpointKey(api *swagger.API, e *swagger.Endpoint) string {
+	return RouteKey(e.Method, swag.ColonPath(endpointPath(api, e)))
+}
+
+// endpointPath returns the swagger path template of endpoint e, with the basePath of api applied
+func endpointPath(api *swagger.API, e *swagger.Endpoint) string {
+	return strings.TrimRight(api.BasePath, "/") + e.Path
+}
+
+func compileEndpoint(loader *gojsonschema.SchemaLoader, n int, e *swagger.Endpoint) *validatorEndpoint {
+	ve := &validatorEndpoint{
+		endpoint:  e,
+		locations: map[string]string{},
+		responses: map[string]*responseSchema{},
+	}
+	var doc map[string]interface{}
+	ve.schema, doc, ve.err = compileSchema(loader, fmt.Sprintf(endpointID, n), buildRequestSchema(e))
+	ve.properties, _ = doc["properties"].(map[string]interface{})
+	for _, p := range e.Parameters {
+		if p.Name == "" {
+			continue
+		}
+		ve.locations[p.Name] = p.In
+		if p.In == "header" {
+			ve.headers = append(ve.headers, p.Name)
+		}
+	}
+
+	for code, response := range e.Responses {
+		rs := &responseSchema{response: response}
+		if response.Schema != nil && response.Schema.Type != "file" {
+			rs.schema, _, rs.err = compileSchema(loader, fmt.Sprintf(responseID, n, code), response.Schema)
+		}
+		ve.responses[code] = rs
+	}
+	return ve
+}
+
+// setCollectionFormats records the collectionFormat of array parameters in their properties, for loadValueForKey.
+// It returns an error for unknown formats.
+func setCollectionFormats(properties map[string]interface{}, formats map[string]string) error {
+	for name, format := range formats {
+		if _, ok := collectionFormats[format]; !ok && format != "multi" {
+			return fmt.Errorf("unknown collection format %s of parameter %s", format, name)
+		}
+		if prop, ok := properties[name].(map[string]interface{}); ok && prop["type"] == "array" {
+			prop["collectionFormat"] = format
+		}
+	}
+	return nil
+}
+
+// compileSchema compiles schema under the $id id, with references to the shared definitions.
+// The decoded schema document is returned along with the compiled schema.
+func compileSchema(loader *gojsonschema.SchemaLoader, id string, schema interface{}) (*gojsonschema.Schema, map[string]interface{}, error) {
+	ref, err := gojsonschema.NewGoLoader(schema).LoadJSON()
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, ok := ref.(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("schema %s is not an object", id)
+	}
+	doc["$id"] = id
+	resolveDefinitions(doc)
+
+	if err := loader.AddSchemas(gojsonschema.NewGoLoader(doc)); err != nil {
+		return nil, doc, err
+	}
+	compiled, err := loader.Compile(gojsonschema.NewReferenceLoader(id))
+	return compiled, doc, err
+}
+
+// resolveDefinitions points local "#/definitions/..." references of an endpoint schema to the shared definitions
+func resolveDefinitions(node interface{}) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			if ref, ok := v.(string); ok && k == "$ref" && strings.HasPrefix(ref, "#/definitions/") {
+				n[k] = definitionsID + ref
+			} else {
+				resolveDefinitions(v)
+			}
+		}
+	case []interface{}:
+		for _, v := range n {
+			resolveDefinitions(v)
+		}
+	}
+}
+
+func (v *Validator) hasRoute(routeKey string) bool {
+	_, found := v.endpoints[routeKey]
+	return found
+}
+
+// ValidateRequest validates r against the endpoint registered under routeKey.
+// pathParams holds the path parameters extracted by the router.
+// Requests for routes that are not in the swagger spec are returned as valid with a nil Endpoint.
+func (v *Validator) ValidateRequest(r *http.Request, routeKey string, pathParams map[string]string) *Result {
+	ve, found := v.endpoints[routeKey]
+	if !found {
+		return &Result{}
+	}
+	result := &Result{Endpoint: ve.endpoint}
+	if ve.err != nil {
+		result.Error = &ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "swagger document " + ve.err.Error(),
+		}
+		return result
+	}
+
+	if errResp := checkConsumes(r, ve.endpoint.Consumes); errResp != nil {
+		result.Error = errResp
+		return result
+	}
+	if v.options.AcceptValidation {
+		if errResp := checkAccept(r, ve.endpoint.Produces); errResp != nil {
+			result.Error = errResp
+			return result
+		}
+	}
+
+	document, errResp := v.buildDocument(r, ve, pathParams)
+	if errResp != nil {
+		result.Error = errResp
+		return result
+	}
+
+	details := v.validateFiles(r, ve)
+
+	res, err := ve.schema.Validate(gojsonschema.NewGoLoader(document))
+	if err != nil {
+		result.Error = &ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "swagger document " + err.Error(),
+		}
+		return result
+	}
+	for field, description := range v.flattenErrors(res.Errors()) {
+		details[field] = description
+	}
+	if len(details) > 0 {
+		result.Error = &ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Validation error",
+			Details:    details,
+		}
+	}
+	return result
+}
+
+// buildDocument assembles the document validated against the endpoint schema from
+// path params, header params, query, form values and body of the request
+func (v *Validator) buildDocument(r *http.Request, ve *validatorEndpoint, pathParams map[string]string) (map[string]interface{}, *ErrorResponse) {
+	properties := ve.properties
+	document := map[string]interface{}{}
+	// details collects parameters received in a form their collectionFormat does not allow
+	details := map[string]string{}
+	load := func(k string, v []string) {
+		value, err := loadValueForKey(properties, k, v)
+		if err != nil {
+			details[k] = err.Error()
+			return
+		}
+		document[k] = value
+	}
+
+	for k, v := range pathParams {
+		load(k, []string{v})
+	}
+	for _, k := range ve.headers {
+		// repeated header lines are equivalent to a single line of comma separated values
+		if v := headerValues(r.Header, k); len(v) > 0 {
+			load(k, []string{strings.Join(v, ",")})
+		}
+	}
+	// only declared parameters are loaded, so a query or form value cannot stand in for a header, path or body parameter
+	for k, v := range r.URL.Query() {
+		if ve.locations[k] == "query" {
+			load(k, v)
+		}
+	}
+
+	// reject bodies declared too large before reading any of it, bodies of unknown length are read up to the limit
+	maxSize := ve.maxBodySize
+	if maxSize > 0 && r.ContentLength > maxSize {
+		return nil, bodyTooLarge(maxSize)
+	}
+
+	contentType := mediaType(r.Header.Get("Content-Type"))
+
+	// For muiltipart form, handle params and file uploads
+	if contentType == "multipart/form-data" {
+		if maxSize > 0 {
+			r.Body = http.MaxBytesReader(nil, r.Body, maxSize)
+		}
+		if err := r.ParseMultipartForm(MaxMemory); isMaxBytesError(err) {
+			return nil, bodyTooLarge(maxSize)
+		}
+
+		for k, v := range r.PostForm {
+			if ve.locations[k] == "formData" {
+				load(k, v)
+			}
+		}
+		if r.MultipartForm != nil && r.MultipartForm.File != nil {
+			for k := range r.MultipartForm.File {
+				if ve.locations[k] == "formData" {
+					document[k] = "x"
+				}
+			}
+		}
+	} else if contentType == "application/x-www-form-urlencoded" {
+		if maxSize > 0 {
+			r.Body = http.MaxBytesReader(nil, r.Body, maxSize)
+		}
+		if err := r.ParseForm(); isMaxBytesError(err) {
+			return nil, bodyTooLarge(maxSize)
+		}
+
+		// form fields are formData parameters of the endpoint, validated like query parameters
+		for k, v := range r.PostForm {
+			if ve.locations[k] == "formData" {
+				load(k, v)
+			}
+		}
+	} else if hasBody(r) {
+		// For all other types parse body as json, if possible, whether its length is known or not, e.g. chunked
+
+		// read the request body to a variable, up to the maximum size
+		reader := io.Reader(r.Body)
+		if maxSize > 0 {
+			reader = io.LimitReader(r.Body, maxSize+1)
+		}
+		b, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, &ErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Validation error",
+				Details: map[string]string{
+					"body": "Failed to read request body",
+				},
+			}
+		}
+		if maxSize > 0 && int64(len(b)) > maxSize {
+			return nil, bodyTooLarge(maxSize)
+		}
+
+		//reset the request body to the original unread state
+		r.Body = ioutil.NopCloser(bytes.NewBuffer(b))
+
+		// a body of unknown length may turn out to be empty
+		if len(b) > 0 {
+			var body interface{}
+			err = decodeJSON(b, &body)
+			// TODO Consider different error cases: Empty Body, Invalid JSON, Form Data
+			if err != nil {
+				return nil, &ErrorResponse{
+					StatusCode: http.StatusBadRequest,
+					Message:    "Validation error",
+					Details: map[string]string{
+						"body": "Invalid JSON format",
+					},
+				}
+			}
+			document["body"] = body
+		}
+	}
+
+	if len(details) > 0 {
+		return nil, &ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Validation error",
+			Details:    details,
+		}
+	}
+	return document, nil
+}
+
+// decodeJSON decodes numbers as json.Number, so large integers are validated on their exact value instead of a float64
+func decodeJSON(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// like json.Unmarshal, reject anything after the first value
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+func bodyTooLarge(maxSize int64) *ErrorResponse {
+	return &ErrorResponse{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Message:    "Request entity too large",
+		Details: map[string]string{
+			"body": fmt.Sprintf("Request body exceeds the maximum size of %d bytes", maxSize),
+		},
+	}
+}
+
+func isMaxBytesError(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// hasBody returns true if r has a body, including bodies of unknown length, e.g. with chunked transfer encoding
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+// headerValues returns the values of the header name, matched case insensitively
+// even when header keys were set without canonicalization
+func headerValues(header http.Header, name string) []string {
+	if v := header.Values(name); len(v) > 0 {
+		return v
+	}
+	for k, v := range header {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func (v *Validator) flattenErrors(resultErrors []gojsonschema.ResultError) map[string]string {
+	errors := map[string]string{}
+	for _, err := range resultErrors {
+		description := describe(v.locale, err)
+		details := err.Details()
+
+		field := details["field"].(string)
+		if val, ok := details["property"]; ok {
+			field += "." + val.(string)
+		}
+		field = strings.TrimPrefix(field, "body.")
+		field = strings.TrimPrefix(field, "(root).")
+		errors[field] = description
+	}
+	return errors
+}
+
+// mediaType strips any options off the content type, e.g. `; charset=UTF-8`
+func mediaType(contentType string) string {
+	for i, ch := range contentType {
+		if ch == ' ' || ch == ';' {
+			return contentType[:i]
+		}
+	}
+	return contentType
+}
@@ -0,0 +1,874 @@
+package swagvalidator_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+
+	swag "github.com/miketonks/swag"
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+	"github.com/stretchr/testify/assert"
+
+	sv "github.com/miketonks/swag-validator"
+)
+
+func TestValidateRequest(t *testing.T) {
+	api := swag.New(
+		swag.BasePath("/api"),
+		swag.Endpoints(endpoint.New("POST", "/pet/{id}", "Test the validator",
+			endpoint.Handler(func() {}),
+			endpoint.Path("id", "integer", "int64", ""),
+			endpoint.Query("limit", "integer", "", "", false),
+			endpoint.Body(nested{}, "Validation body", true),
+		)))
+
+	v := sv.NewValidator(api)
+
+	t.Run("Valid request", func(t *testing.T) {
+		req := preparePostRequest("/api/pet/10?limit=5", nested{Foo: "bar"})
+		result := v.ValidateRequest(req, sv.RouteKey("POST", "/api/pet/:id"), map[string]string{"id": "10"})
+		assert.True(t, result.Valid())
+		assert.NotNil(t, result.Endpoint)
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		req := preparePostRequest("/api/pet/foo?limit=bar", nested{})
+		result := v.ValidateRequest(req, sv.RouteKey("POST", "/api/pet/:id"), map[string]string{"id": "foo"})
+		assert.False(t, result.Valid())
+		assert.Equal(t, http.StatusBadRequest, result.Error.StatusCode)
+		assert.Equal(t, map[string]string{
+			"id":    "Invalid type. Expected: integer, given: string",
+			"limit": "Invalid type. Expected: integer, given: string",
+			"foo":   "foo is required",
+		}, result.Error.Details)
+	})
+
+	t.Run("Route not in spec", func(t *testing.T) {
+		req := preparePostRequest("/api/other", nested{})
+		result := v.ValidateRequest(req, sv.RouteKey("POST", "/api/other"), nil)
+		assert.True(t, result.Valid())
+		assert.Nil(t, result.Endpoint)
+	})
+}
+
+func TestValidateResponse(t *testing.T) {
+	api := swag.New(
+		swag.Endpoints(endpoint.New("GET", "/pet/{id}", "Test the validator",
+			endpoint.Handler(func() {}),
+			endpoint.Path("id", "integer", "int64", ""),
+			endpoint.Response(http.StatusOK, nested{}, "ok",
+				endpoint.Header("X-Rate-Limit", "integer", "int32", "calls per hour allowed"),
+				endpoint.Header("X-Request-Id", "string", "", "request id"),
+			),
+		)))
+
+	v := sv.NewValidator(api)
+	key := sv.RouteKey("GET", "/pet/:id")
+
+	header := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return h
+	}
+
+	t.Run("Valid response", func(t *testing.T) {
+		result := v.ValidateResponse(key, http.StatusOK, header("X-Rate-Limit", "100", "X-Request-Id", "abc"), []byte(`{"foo":"bar"}`))
+		assert.True(t, result.Valid())
+		assert.NotNil(t, result.Endpoint)
+	})
+
+	t.Run("Invalid headers", func(t *testing.T) {
+		result := v.ValidateResponse(key, http.StatusOK, header("X-Rate-Limit", "lots"), []byte(`{"foo":"bar"}`))
+		assert.False(t, result.Valid())
+		assert.Equal(t, http.StatusInternalServerError, result.Error.StatusCode)
+		assert.Equal(t, map[string]string{
+			"X-Rate-Limit": "Invalid type. Expected: integer, given: string",
+			"X-Request-Id": "X-Request-Id is required",
+		}, result.Error.Details)
+	})
+
+	t.Run("Header out of range", func(t *testing.T) {
+		result := v.ValidateResponse(key, http.StatusOK, header("X-Rate-Limit", "99999999999", "X-Request-Id", "abc"), []byte(`{"foo":"bar"}`))
+		assert.False(t, result.Valid())
+		assert.Equal(t, map[string]string{
+			"X-Rate-Limit": "Value out of range for int32",
+		}, result.Error.Details)
+	})
+
+	t.Run("Invalid body", func(t *testing.T) {
+		result := v.ValidateResponse(key, http.StatusOK, header("X-Rate-Limit", "100", "X-Request-Id", "abc"), []byte(`[]`))
+		assert.False(t, result.Valid())
+		assert.Equal(t, map[string]string{
+			"body": "Invalid type. Expected: object, given: array",
+		}, result.Error.Details)
+	})
+
+	t.Run("Body that is not JSON is not validated", func(t *testing.T) {
+		result := v.ValidateResponse(key, http.StatusOK, header("X-Rate-Limit", "100", "X-Request-Id", "abc", "Content-Type", "application/xml"), []byte(`<pet/>`))
+		assert.True(t, result.Valid())
+	})
+
+	t.Run("JSON media types are validated", func(t *testing.T) {
+		result := v.ValidateResponse(key, http.StatusOK, header("X-Rate-Limit", "100", "X-Request-Id", "abc", "Content-Type", "application/problem+json"), []byte(`[]`))
+		assert.False(t, result.Valid())
+	})
+
+	t.Run("Undocumented status", func(t *testing.T) {
+		result := v.ValidateResponse(key, http.StatusNotFound, http.Header{}, []byte(`not json`))
+		assert.True(t, result.Valid())
+	})
+}
+
+func TestDocumentedStatus(t *testing.T) {
+	api := swag.New(
+		swag.Endpoints(
+			endpoint.New("GET", "/pet", "Documents 200",
+				endpoint.Handler(func() {}),
+				endpoint.Response(http.StatusOK, nested{}, "ok"),
+			),
+			endpoint.New("POST", "/pet", "Has a default response",
+				endpoint.Handler(func() {}),
+				endpoint.Response(http.StatusCreated, nested{}, "created"),
+			),
+		))
+	api.Paths["/pet"].Post.Responses["default"] = swagger.Response{Description: "error"}
+
+	v := sv.NewValidator(api)
+
+	assert.True(t, v.DocumentedStatus(sv.RouteKey("GET", "/pet"), http.StatusOK))
+	assert.False(t, v.DocumentedStatus(sv.RouteKey("GET", "/pet"), http.StatusNotFound))
+	assert.True(t, v.DocumentedStatus(sv.RouteKey("POST", "/pet"), http.StatusConflict))
+	assert.True(t, v.DocumentedStatus(sv.RouteKey("GET", "/other"), http.StatusNotFound))
+}
+
+func TestValidateRequestHeaders(t *testing.T) {
+	api := swag.New(
+		swag.Endpoints(
+			endpoint.New("GET", "/pet", "Test the validator",
+				endpoint.Handler(func() {}),
+				endpoint.RequestHeader("X-Tenant-ID", "string", "uuid", "", true),
+				endpoint.RequestHeader("x-page-size", "integer", "int32", "", false),
+			),
+			endpoint.New("POST", "/pet", "Test the validator with a form",
+				endpoint.Handler(func() {}),
+				endpoint.RequestHeader("X-Tenant-ID", "string", "uuid", "", true),
+				endpoint.FormData("name", "string", "", "", false),
+				endpoint.Consumes("application/x-www-form-urlencoded"),
+			),
+		))
+
+	v := sv.NewValidator(api)
+
+	testTable := []struct {
+		description     string
+		headers         map[string]string
+		query           url.Values
+		form            url.Values
+		expectedDetails map[string]string
+	}{
+		{
+			description: "Valid headers",
+			headers:     map[string]string{"X-Tenant-ID": testUUID, "X-Page-Size": "10"},
+		},
+		{
+			description: "Header names are case insensitive",
+			headers:     map[string]string{"x-tenant-id": testUUID, "X-PAGE-SIZE": "10"},
+		},
+		{
+			description:     "Missing required header",
+			headers:         map[string]string{"X-Page-Size": "10"},
+			expectedDetails: map[string]string{"X-Tenant-ID": "X-Tenant-ID is required"},
+		},
+		{
+			description:     "Missing required header sent in the query",
+			query:           url.Values{"X-Tenant-ID": {testUUID}},
+			expectedDetails: map[string]string{"X-Tenant-ID": "X-Tenant-ID is required"},
+		},
+		{
+			description:     "Missing required header sent in the form",
+			form:            url.Values{"X-Tenant-ID": {testUUID}, "name": {"Rex"}},
+			expectedDetails: map[string]string{"X-Tenant-ID": "X-Tenant-ID is required"},
+		},
+		{
+			description: "Invalid header values",
+			headers:     map[string]string{"X-Tenant-ID": "foo", "X-Page-Size": "ten"},
+			expectedDetails: map[string]string{
+				"X-Tenant-ID": "Field does not match format 'uuid'",
+				"x-page-size": "Invalid type. Expected: integer, given: string",
+			},
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/pet?"+tc.query.Encode(), nil)
+			if tc.form != nil {
+				req, _ = http.NewRequest("POST", "/pet", strings.NewReader(tc.form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			for k, v := range tc.headers {
+				req.Header[k] = []string{v}
+			}
+			result := v.ValidateRequest(req, sv.RouteKey(req.Method, "/pet"), nil)
+			if tc.expectedDetails == nil {
+				assert.True(t, result.Valid())
+				return
+			}
+			assert.False(t, result.Valid())
+			assert.Equal(t, tc.expectedDetails, result.Error.Details)
+		})
+	}
+}
+
+func TestValidateRequestConsumes(t *testing.T) {
+	api := swag.New(
+		swag.Endpoints(
+			endpoint.New("POST", "/pet", "Consumes json",
+				endpoint.Handler(func() {}),
+			),
+			endpoint.New("POST", "/upload", "Consumes forms and any text",
+				endpoint.Handler(func() {}),
+				endpoint.Consumes("multipart/form-data", "application/x-www-form-urlencoded", "text/*"),
+			),
+			endpoint.New("POST", "/photo", "Consumes any bytes",
+				endpoint.Handler(func() {}),
+				endpoint.Consumes("application/octet-stream"),
+			),
+		))
+
+	v := sv.NewValidator(api)
+
+	testTable := []struct {
+		description    string
+		path           string
+		contentType    string
+		expectedStatus int
+	}{
+		{"Default json", "/pet", "application/json", 0},
+		{"Media type parameters are ignored", "/pet", "Application/JSON; charset=utf-8", 0},
+		{"No content type is application/octet-stream", "/pet", "", http.StatusUnsupportedMediaType},
+		{"No content type, form not sent", "/upload", "", http.StatusUnsupportedMediaType},
+		{"No content type, octet-stream consumed", "/photo", "", 0},
+		{"Unsupported media type", "/pet", "text/plain", http.StatusUnsupportedMediaType},
+		{"Form", "/upload", "application/x-www-form-urlencoded", 0},
+		{"Wildcard media range", "/upload", "text/csv", 0},
+		{"Json not consumed", "/upload", "application/json", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			req := preparePostRequest(tc.path, nested{Foo: "bar"})
+			req.Header.Del("Content-Type")
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			result := v.ValidateRequest(req, sv.RouteKey("POST", tc.path), nil)
+			if tc.expectedStatus == 0 {
+				if result.Error != nil {
+					assert.NotEqual(t, http.StatusUnsupportedMediaType, result.Error.StatusCode)
+				}
+				return
+			}
+			assert.False(t, result.Valid())
+			assert.Equal(t, tc.expectedStatus, result.Error.StatusCode)
+		})
+	}
+
+	t.Run("Details list the supported media types", func(t *testing.T) {
+		req := preparePostRequest("/pet", nested{Foo: "bar"})
+		req.Header.Set("Content-Type", "text/plain")
+		result := v.ValidateRequest(req, sv.RouteKey("POST", "/pet"), nil)
+		assert.Equal(t, "Unsupported media type", result.Error.Message)
+		assert.Equal(t, map[string]string{"Content-Type": "Must be one of: application/json"}, result.Error.Details)
+	})
+}
+
+func TestValidateRequestAccept(t *testing.T) {
+	api := swag.New(
+		swag.Endpoints(
+			endpoint.New("GET", "/pet", "Produces json",
+				endpoint.Handler(func() {}),
+			),
+			endpoint.New("GET", "/photo", "Produces images",
+				endpoint.Handler(func() {}),
+				endpoint.Produces("image/png", "image/jpeg"),
+			),
+		))
+
+	v := sv.NewValidator(api, sv.SetAcceptValidation(true))
+
+	testTable := []struct {
+		description string
+		path        string
+		accept      string
+		acceptable  bool
+	}{
+		{"No accept header", "/pet", "", true},
+		{"Exact media type", "/pet", "application/json", true},
+		{"Any media type", "/pet", "*/*", true},
+		{"Media range", "/pet", "text/html, application/*;q=0.8", true},
+		{"Unsatisfiable", "/pet", "text/html, text/plain", false},
+		{"Excluded by q=0", "/pet", "application/json;q=0, */*;q=0.5", false},
+		{"Most specific range wins", "/photo", "image/*;q=0, image/jpeg", true},
+		{"Media type parameters are ignored", "/photo", "image/png; q=0.9; foo=bar", true},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tc.path, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			result := v.ValidateRequest(req, sv.RouteKey("GET", tc.path), nil)
+			assert.Equal(t, tc.acceptable, result.Valid())
+		})
+	}
+
+	t.Run("Details list the supported media types", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/photo", nil)
+		req.Header.Set("Accept", "application/json")
+		result := v.ValidateRequest(req, sv.RouteKey("GET", "/photo"), nil)
+		assert.Equal(t, http.StatusNotAcceptable, result.Error.StatusCode)
+		assert.Equal(t, map[string]string{"Accept": "Must accept one of: image/png, image/jpeg"}, result.Error.Details)
+	})
+
+	t.Run("Off by default", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/pet", nil)
+		req.Header.Set("Accept", "text/html")
+		result := sv.NewValidator(api).ValidateRequest(req, sv.RouteKey("GET", "/pet"), nil)
+		assert.True(t, result.Valid())
+	})
+}
+
+type testFile struct {
+	field, name, contentType, content string
+}
+
+func prepareMultipartRequest(url string, files ...testFile) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, f := range files {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, f.field, f.name))
+		if f.contentType != "" {
+			h.Set("Content-Type", f.contentType)
+		}
+		part, _ := writer.CreatePart(h)
+		part.Write([]byte(f.content))
+	}
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", url, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestValidateRequestFiles(t *testing.T) {
+	api := swag.New(
+		swag.Endpoints(
+			endpoint.New("POST", "/upload", "Upload images",
+				endpoint.Handler(func() {}),
+				endpoint.FormData("image", "file", "", "", true),
+				endpoint.FormData("note", "string", "", "", false),
+				endpoint.Consumes("multipart/form-data"),
+			),
+			endpoint.New("POST", "/attach", "Upload attachments",
+				endpoint.Handler(func() {}),
+				endpoint.FormData("image", "file", "", "", true),
+				endpoint.Consumes("multipart/form-data"),
+			),
+		))
+
+	limits := sv.FileLimits{
+		MaxSize:   32,
+		MaxFiles:  2,
+		MIMETypes: []string{"image/*"},
+	}
+	v := sv.NewValidator(api,
+		sv.SetFileLimits("POST", "/upload", "image", limits),
+		sv.SetFileLimits("POST", "/upload", "note", limits),
+	)
+	png := "\x89PNG\r\n\x1a\n"
+
+	testTable := []struct {
+		description     string
+		path            string
+		files           []testFile
+		expectedDetails map[string]string
+	}{
+		{
+			description: "Valid file",
+			path:        "/upload",
+			files:       []testFile{{"image", "a.png", "image/png", png}},
+		},
+		{
+			description:     "Missing file",
+			path:            "/upload",
+			expectedDetails: map[string]string{"image": "image is required"},
+		},
+		{
+			description: "Too many files",
+			path:        "/upload",
+			files: []testFile{
+				{"image", "a.png", "image/png", png},
+				{"image", "b.png", "image/png", png},
+				{"image", "c.png", "image/png", png},
+			},
+			expectedDetails: map[string]string{"image": "At most 2 files allowed"},
+		},
+		{
+			description:     "File too large",
+			path:            "/upload",
+			files:           []testFile{{"image", "a.png", "image/png", png + strings.Repeat("x", 32)}},
+			expectedDetails: map[string]string{"image": "File a.png exceeds the maximum size of 32 bytes"},
+		},
+		{
+			description:     "Declared type not allowed",
+			path:            "/upload",
+			files:           []testFile{{"image", "a.txt", "text/plain", png}},
+			expectedDetails: map[string]string{"image": "File a.txt has type text/plain, must be one of: image/*"},
+		},
+		{
+			description:     "Sniffed type not allowed",
+			path:            "/upload",
+			files:           []testFile{{"image", "a.png", "image/png", "not an image"}},
+			expectedDetails: map[string]string{"image": "File a.png has content of type text/plain, must be one of: image/*"},
+		},
+		{
+			description: "Field not declared as a file is not checked",
+			path:        "/upload",
+			files: []testFile{
+				{"image", "a.png", "image/png", png},
+				{"note", "a.txt", "text/plain", "not an image"},
+			},
+		},
+		{
+			description: "Limits of another endpoint do not apply",
+			path:        "/attach",
+			files:       []testFile{{"image", "a.txt", "text/plain", strings.Repeat("x", 64)}},
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			result := v.ValidateRequest(prepareMultipartRequest(tc.path, tc.files...), sv.RouteKey("POST", tc.path), nil)
+			if tc.expectedDetails == nil {
+				assert.True(t, result.Valid())
+				return
+			}
+			assert.False(t, result.Valid())
+			assert.Equal(t, tc.expectedDetails, result.Error.Details)
+		})
+	}
+}
+
+func TestValidateRequestForm(t *testing.T) {
+	api := swag.New(
+		swag.Endpoints(endpoint.New("POST", "/pet", "Test form fields",
+			endpoint.Handler(func() {}),
+			endpoint.FormDataMap(map[string]swagger.Parameter{
+				"age":  {Type: "integer", Format: "int32", Required: true},
+				"tags": {Type: "array", Items: &swagger.Items{Type: "string"}, MinItems: 2},
+			}),
+			endpoint.Consumes("application/x-www-form-urlencoded", "multipart/form-data"),
+		)))
+
+	v := sv.NewValidator(api)
+	key := sv.RouteKey("POST", "/pet")
+
+	urlencoded := func(form url.Values) *http.Request {
+		req, _ := http.NewRequest("POST", "/pet", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+	multipartForm := func(form url.Values) *http.Request {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for k, values := range form {
+			for _, value := range values {
+				writer.WriteField(k, value)
+			}
+		}
+		writer.Close()
+		req, _ := http.NewRequest("POST", "/pet", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	for name, prepare := range map[string]func(url.Values) *http.Request{
+		"urlencoded": urlencoded,
+		"multipart":  multipartForm,
+	} {
+		t.Run(name+" valid", func(t *testing.T) {
+			result := v.ValidateRequest(prepare(url.Values{"age": {"3"}, "tags": {"a", "b"}}), key, nil)
+			assert.True(t, result.Valid(), "%+v", result.Error)
+		})
+
+		t.Run(name+" invalid", func(t *testing.T) {
+			result := v.ValidateRequest(prepare(url.Values{"age": {"three"}, "tags": {"a"}}), key, nil)
+			assert.False(t, result.Valid())
+			assert.Equal(t, map[string]string{
+				"age":  "Invalid type. Expected: integer, given: string",
+				"tags": "Array must have at least 2 items",
+			}, result.Error.Details)
+		})
+	}
+}
+
+func TestValidateRequestCollectionFormat(t *testing.T) {
+	array := func(name string) swagger.Parameter {
+		return swagger.Parameter{Name: name, In: "query", Type: "array", Items: &swagger.Items{Type: "integer"}, MaxItems: 3}
+	}
+	e := endpoint.New("GET", "/pet", "Test collection formats", endpoint.Handler(func() {}))
+	e.Parameters = append(e.Parameters, array("csv"), array("ssv"), array("tsv"), array("pipes"), array("multi"), array("default"))
+	header := array("X-Ids")
+	header.In = "header"
+	e.Parameters = append(e.Parameters, header)
+	other := endpoint.New("GET", "/owner", "Test collection formats of another endpoint", endpoint.Handler(func() {}))
+	other.Parameters = append(other.Parameters, array("pipes"))
+	api := swag.New(swag.Endpoints(e, other))
+
+	v := sv.NewValidator(api,
+		sv.SetCollectionFormat("GET", "/pet", "csv", "csv"),
+		sv.SetCollectionFormat("GET", "/pet", "ssv", "ssv"),
+		sv.SetCollectionFormat("GET", "/pet", "tsv", "tsv"),
+		sv.SetCollectionFormat("GET", "/pet", "pipes", "pipes"),
+		sv.SetCollectionFormat("GET", "/pet", "multi", "multi"),
+		sv.SetCollectionFormat("GET", "/pet", "X-Ids", "csv"),
+	)
+
+	testTable := []struct {
+		description     string
+		path            string
+		query           url.Values
+		header          http.Header
+		expectedDetails map[string]string
+	}{
+		{
+			description: "Values split by their collection format",
+			path:        "/pet",
+			query: url.Values{
+				"csv":     {"1,2"},
+				"ssv":     {"1 2"},
+				"tsv":     {"1\t2"},
+				"pipes":   {"1|2|3"},
+				"multi":   {"1", "2"},
+				"default": {"1", "2"},
+			},
+		},
+		{
+			description: "Separators of other formats are not split",
+			path:        "/pet",
+			query:       url.Values{"pipes": {"1,2"}},
+			expectedDetails: map[string]string{
+				"pipes.0": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description: "Repeated parameters for a single value format",
+			path:        "/pet",
+			query:       url.Values{"pipes": {"1", "2"}, "csv": {"1", "2"}},
+			expectedDetails: map[string]string{
+				"pipes": "Invalid collection format. Expected: a single pipes separated value, given: 2 values",
+				"csv":   "Invalid collection format. Expected: a single csv separated value, given: 2 values",
+			},
+		},
+		{
+			description: "Multi values are not split",
+			path:        "/pet",
+			query:       url.Values{"multi": {"1,2"}},
+			expectedDetails: map[string]string{
+				"multi.0": "Invalid type. Expected: integer, given: string",
+			},
+		},
+		{
+			description: "Default format accepts comma separated values",
+			path:        "/pet",
+			query:       url.Values{"default": {"1,2,3,4"}},
+			expectedDetails: map[string]string{
+				"default": "Array must have at most 3 items",
+			},
+		},
+		{
+			description: "Repeated header lines are combined",
+			path:        "/pet",
+			header:      http.Header{"X-Ids": {"1,2", "3"}},
+		},
+		{
+			description: "Repeated header lines are combined before the limits are checked",
+			path:        "/pet",
+			header:      http.Header{"X-Ids": {"1,2", "3,4"}},
+			expectedDetails: map[string]string{
+				"X-Ids": "Array must have at most 3 items",
+			},
+		},
+		{
+			description: "Formats of another endpoint do not apply",
+			path:        "/owner",
+			query:       url.Values{"pipes": {"1,2"}},
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tc.path+"?"+tc.query.Encode(), nil)
+			for k, values := range tc.header {
+				req.Header[k] = values
+			}
+			result := v.ValidateRequest(req, sv.RouteKey("GET", tc.path), nil)
+			if tc.expectedDetails == nil {
+				assert.True(t, result.Valid(), "%+v", result.Error)
+				return
+			}
+			assert.False(t, result.Valid())
+			assert.Equal(t, tc.expectedDetails, result.Error.Details)
+		})
+	}
+
+	t.Run("Unknown collection format", func(t *testing.T) {
+		v := sv.NewValidator(api, sv.SetCollectionFormat("GET", "/pet", "csv", "commas"))
+
+		req, _ := http.NewRequest("GET", "/pet", nil)
+		result := v.ValidateRequest(req, sv.RouteKey("GET", "/pet"), nil)
+		assert.False(t, result.Valid())
+		assert.Equal(t, http.StatusInternalServerError, result.Error.StatusCode)
+		assert.Equal(t, "swagger document unknown collection format commas of parameter csv", result.Error.Message)
+	})
+}
+
+func TestValidateRequestBodyOfUnknownLength(t *testing.T) {
+	api := swag.New(
+		swag.Endpoints(endpoint.New("POST", "/pet", "Test the validator",
+			endpoint.Handler(func() {}),
+			endpoint.Body(nested{}, "Validation body", true),
+		)))
+
+	v := sv.NewValidator(api, sv.SetMaxBodySize(32))
+	key := sv.RouteKey("POST", "/pet")
+
+	// chunked requests have an unknown ContentLength of -1
+	chunked := func(body string) *http.Request {
+		req, _ := http.NewRequest("POST", "/pet", io.MultiReader(strings.NewReader(body)))
+		req.ContentLength = -1
+		req.TransferEncoding = []string{"chunked"}
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	t.Run("Valid body is restored for the handler", func(t *testing.T) {
+		req := chunked(`{"foo":"bar"}`)
+		result := v.ValidateRequest(req, key, nil)
+		assert.True(t, result.Valid(), "%+v", result.Error)
+		b, _ := io.ReadAll(req.Body)
+		assert.Equal(t, `{"foo":"bar"}`, string(b))
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		result := v.ValidateRequest(chunked(`{"foo":`), key, nil)
+		assert.False(t, result.Valid())
+		assert.Equal(t, http.StatusBadRequest, result.Error.StatusCode)
+		assert.Equal(t, map[string]string{"body": "Invalid JSON format"}, result.Error.Details)
+	})
+
+	t.Run("Invalid body", func(t *testing.T) {
+		result := v.ValidateRequest(chunked(`{}`), key, nil)
+		assert.False(t, result.Valid())
+		assert.Equal(t, map[string]string{"foo": "foo is required"}, result.Error.Details)
+	})
+
+	t.Run("Empty body", func(t *testing.T) {
+		result := v.ValidateRequest(chunked(""), key, nil)
+		assert.False(t, result.Valid())
+		assert.Equal(t, map[string]string{"body": "body is required"}, result.Error.Details)
+	})
+
+	t.Run("Body too large", func(t *testing.T) {
+		result := v.ValidateRequest(chunked(`{"foo":"`+strings.Repeat("x", 32)+`"}`), key, nil)
+		assert.False(t, result.Valid())
+		assert.Equal(t, http.StatusRequestEntityTooLarge, result.Error.StatusCode)
+		assert.Equal(t, map[string]string{"body": "Request body exceeds the maximum size of 32 bytes"}, result.Error.Details)
+	})
+}
+
+// unreadBody fails the test if the body is read
+type unreadBody struct {
+	t *testing.T
+}
+
+func (b unreadBody) Read([]byte) (int, error) {
+	b.t.Error("body should not be read")
+	return 0, io.EOF
+}
+
+func (b unreadBody) Close() error {
+	return nil
+}
+
+func TestValidateRequestBodyLimits(t *testing.T) {
+	api := swag.New(
+		swag.BasePath("/api"),
+		swag.Endpoints(
+			endpoint.New("POST", "/pet", "Uses the global limit",
+				endpoint.Handler(func() {}),
+				endpoint.Body(nested{}, "Validation body", true),
+			),
+			endpoint.New("POST", "/pet/{id}", "Overrides the global limit",
+				endpoint.Handler(func() {}),
+				endpoint.Path("id", "integer", "", ""),
+				endpoint.Body(nested{}, "Validation body", true),
+			),
+			endpoint.New("POST", "/form", "Forms use the global limit",
+				endpoint.Handler(func() {}),
+				endpoint.FormData("foo", "string", "", "", false),
+				endpoint.Consumes("application/x-www-form-urlencoded", "multipart/form-data"),
+			),
+		))
+
+	v := sv.NewValidator(api,
+		sv.SetMaxBodySize(16),
+		sv.SetEndpointMaxBodySize("POST", "/api/pet/{id}", 64),
+	)
+	large := `{"foo":"` + strings.Repeat("x", 32) + `"}`
+
+	t.Run("Declared length over the limit is rejected before reading", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/api/pet", nil)
+		req.Body = unreadBody{t}
+		req.ContentLength = 1 << 30
+		req.Header.Set("Content-Type", "application/json")
+		result := v.ValidateRequest(req, sv.RouteKey("POST", "/api/pet"), nil)
+		assert.False(t, result.Valid())
+		assert.Equal(t, http.StatusRequestEntityTooLarge, result.Error.StatusCode)
+		assert.Equal(t, map[string]string{"body": "Request body exceeds the maximum size of 16 bytes"}, result.Error.Details)
+	})
+
+	t.Run("Endpoint limit overrides the global limit", func(t *testing.T) {
+		result := v.ValidateRequest(preparePostRequest("/api/pet/1", nested{Foo: strings.Repeat("x", 32)}), sv.RouteKey("POST", "/api/pet/:id"), map[string]string{"id": "1"})
+		assert.True(t, result.Valid(), "%+v", result.Error)
+
+		result = v.ValidateRequest(preparePostRequest("/api/pet", nested{Foo: strings.Repeat("x", 32)}), sv.RouteKey("POST", "/api/pet"), nil)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, result.Error.StatusCode)
+	})
+
+	t.Run("Urlencoded form of unknown length over the limit", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/api/form", io.MultiReader(strings.NewReader("foo="+strings.Repeat("x", 32))))
+		req.ContentLength = -1
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		result := v.ValidateRequest(req, sv.RouteKey("POST", "/api/form"), nil)
+		assert.False(t, result.Valid())
+		assert.Equal(t, http.StatusRequestEntityTooLarge, result.Error.StatusCode)
+	})
+
+	t.Run("Multipart form of unknown length over the limit", func(t *testing.T) {
+		req := prepareMultipartRequest("/api/form", testFile{"foo", "foo.txt", "", large})
+		req.ContentLength = -1
+		result := v.ValidateRequest(req, sv.RouteKey("POST", "/api/form"), nil)
+		assert.False(t, result.Valid())
+		assert.Equal(t, http.StatusRequestEntityTooLarge, result.Error.StatusCode)
+	})
+}
+
+func TestValidateRequestLargeIntegers(t *testing.T) {
+	type pet struct {
+		ID int64 `json:"id" maximum:"9007199254740992"`
+	}
+	api := swag.New(
+		swag.Endpoints(endpoint.New("POST", "/pet", "Test the validator",
+			endpoint.Handler(func() {}),
+			endpoint.Body(pet{}, "Validation body", true),
+		)))
+
+	v := sv.NewValidator(api)
+	key := sv.RouteKey("POST", "/pet")
+
+	post := func(body string) *sv.Result {
+		req, _ := http.NewRequest("POST", "/pet", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return v.ValidateRequest(req, key, nil)
+	}
+
+	t.Run("Maximum", func(t *testing.T) {
+		assert.True(t, post(`{"id":9007199254740992}`).Valid())
+
+		// 2^53+1 rounds down to the maximum as a float64
+		result := post(`{"id":9007199254740993}`)
+		assert.False(t, result.Valid())
+		assert.Contains(t, result.Error.Details, "id")
+	})
+
+	t.Run("Trailing data", func(t *testing.T) {
+		result := post(`{"id":1} {}`)
+		assert.False(t, result.Valid())
+		assert.Equal(t, map[string]string{"body": "Invalid JSON format"}, result.Error.Details)
+	})
+}
+
+func TestValidateRequestNumberFormats(t *testing.T) {
+	type numbers struct {
+		Int32  int32   `json:"int32,omitempty"`
+		Int64  int64   `json:"int64,omitempty"`
+		Float  float32 `json:"float,omitempty"`
+		Double float64 `json:"double,omitempty"`
+	}
+	api := swag.New(
+		swag.Endpoints(endpoint.New("POST", "/numbers", "Test number formats",
+			endpoint.Handler(func() {}),
+			endpoint.Query("int32", "integer", "int32", "", false),
+			endpoint.Body(numbers{}, "Validation body", true),
+		)))
+
+	v := sv.NewValidator(api)
+	key := sv.RouteKey("POST", "/numbers")
+
+	testTable := []struct {
+		description     string
+		query           string
+		body            string
+		expectedDetails map[string]string
+	}{
+		{
+			description: "In range",
+			query:       "int32=2147483647",
+			body:        `{"int32":-2147483648,"int64":9223372036854775807,"float":3.4e38,"double":1.7e308}`,
+		},
+		{
+			description: "Out of range in the body",
+			body:        `{"int32":2147483648,"int64":9223372036854775808,"float":3.5e38}`,
+			expectedDetails: map[string]string{
+				"int32": "Value out of range for int32",
+				"int64": "Value out of range for int64",
+				"float": "Value out of range for float",
+			},
+		},
+		{
+			description: "Out of range in the query",
+			query:       "int32=99999999999",
+			body:        `{}`,
+			expectedDetails: map[string]string{
+				"int32": "Value out of range for int32",
+			},
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.description, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/numbers?"+tc.query, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			result := v.ValidateRequest(req, key, nil)
+			if tc.expectedDetails == nil {
+				assert.True(t, result.Valid(), "%+v", result.Error)
+				return
+			}
+			assert.False(t, result.Valid())
+			assert.Equal(t, tc.expectedDetails, result.Error.Details)
+		})
+	}
+}